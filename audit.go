@@ -0,0 +1,221 @@
+// Copyright 2015 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuditEntry records who did what and when.
+type AuditEntry struct {
+	Time     time.Time              `json:"time"`               // when the request was received
+	Identity string                 `json:"identity,omitempty"` // who sent the request (see AuditOptions.Identity)
+	Method   string                 `json:"method"`             // the HTTP method
+	URL      string                 `json:"url"`                // the requested URL
+	Route    string                 `json:"route,omitempty"`    // the name of the route that handled the request, or its methods and pattern
+	Params   map[string]string      `json:"params,omitempty"`   // the URL parameter values
+	Fields   map[string]interface{} `json:"fields,omitempty"`   // the fields submitted in the request body, with sensitive values redacted
+	Status   int                    `json:"status"`             // the response status code
+}
+
+// AuditSink stores audit entries.
+type AuditSink interface {
+	// Audit stores the given audit entry.
+	Audit(entry *AuditEntry) error
+}
+
+// AuditFunc adapts a function into an AuditSink. It is useful for storing audit entries in a database.
+type AuditFunc func(entry *AuditEntry) error
+
+// Audit calls f(entry).
+func (f AuditFunc) Audit(entry *AuditEntry) error {
+	return f(entry)
+}
+
+// AuditWriter returns an AuditSink that writes audit entries to w (e.g. an opened log file), one JSON object per line.
+func AuditWriter(w io.Writer) AuditSink {
+	var mu sync.Mutex
+	return AuditFunc(func(entry *AuditEntry) error {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		_, err = w.Write(append(data, '\n'))
+		return err
+	})
+}
+
+// AuditOptions defines the possible options for the Audit handler.
+type AuditOptions struct {
+	// The HTTP methods of the requests to be audited. It is defaulted to POST, PUT, PATCH and DELETE.
+	Methods     []string
+	// A function returning the identity of the client who sends the request, such as the ID of the authenticated user.
+	Identity    func(*Context) string
+	// The names of the body fields whose values should be redacted (e.g. "password"). Names are case-insensitive.
+	Redact      []string
+	// The routes that should not be audited, specified by their names (or their methods and patterns if they have no names).
+	Exclude     []string
+	// A function used to log the errors returned by the sink.
+	Log         LogFunc
+	// The maximum size of a request body whose fields are recorded. Larger bodies are passed to the handlers
+	// without being buffered, and their fields are not recorded. It is defaulted to 1MB.
+	MaxBodySize int64
+}
+
+// Audit returns a handler that records an audit entry in the given sink for every state-changing request.
+// An audit entry records who sent the request, what route handled it with which parameters and body fields,
+// and when it happened. Body fields are extracted from JSON objects and URL-encoded forms, and the values of
+// the fields listed in AuditOptions.Redact are replaced with "***". Only the bodies of these content types that
+// do not exceed AuditOptions.MaxBodySize are buffered; other bodies are passed to the handlers unchanged.
+//
+// The entry is recorded after the subsequent handlers complete, so the Audit handler is usually registered
+// after the handlers that authenticate the client. For example,
+//
+//   r.Use(authHandler, routing.Audit(routing.AuditWriter(file), routing.AuditOptions{
+//       Identity: func(c *routing.Context) string { return c.Data["user"].(string) },
+//       Redact:   []string{"password"},
+//   }))
+func Audit(sink AuditSink, opts ...AuditOptions) Handler {
+	options := AuditOptions{}
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	methods := make(map[string]bool)
+	if len(options.Methods) == 0 {
+		options.Methods = []string{"POST", "PUT", "PATCH", "DELETE"}
+	}
+	if options.MaxBodySize <= 0 {
+		options.MaxBodySize = 1 << 20
+	}
+	for _, method := range options.Methods {
+		methods[method] = true
+	}
+	redacted := make(map[string]bool)
+	for _, name := range options.Redact {
+		redacted[strings.ToLower(name)] = true
+	}
+	excluded := make(map[string]bool)
+	for _, name := range options.Exclude {
+		excluded[name] = true
+	}
+
+	return func(c *Context) {
+		if !methods[c.Request.Method] {
+			c.Next()
+			return
+		}
+
+		route := c.Route
+		entry := &AuditEntry{
+			Time:   time.Now(),
+			Method: c.Request.Method,
+			URL:    c.Request.URL.RequestURI(),
+		}
+		if contentType := c.Request.Header.Get("Content-Type"); c.Request.Body != nil && hasAuditFields(contentType) {
+			body, _ := ioutil.ReadAll(io.LimitReader(c.Request.Body, options.MaxBodySize+1))
+			if int64(len(body)) > options.MaxBodySize {
+				// pass the read part and the rest of the body to the handlers without recording the fields
+				c.Request.Body = &auditedBody{io.MultiReader(bytes.NewReader(body), c.Request.Body), c.Request.Body}
+			} else {
+				c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+				entry.Fields = auditFields(contentType, body, redacted)
+			}
+		}
+
+		rw := &logResponseWriter{c.Response, http.StatusOK, 0}
+		c.Response = rw
+		c.Next()
+		c.Response = rw.ResponseWriter
+
+		if c.Route != nil && c.Route != route {
+			entry.Route = routeKey(c.Route)
+			if excluded[entry.Route] {
+				return
+			}
+		}
+		if options.Identity != nil {
+			entry.Identity = options.Identity(c)
+		}
+		if len(c.Params) > 0 {
+			entry.Params = copyParams(c.Params)
+		}
+		entry.Status = rw.status
+
+		if err := sink.Audit(entry); err != nil && options.Log != nil {
+			options.Log("audit failed: %v", err)
+		}
+	}
+}
+
+// hasAuditFields checks if the fields of a request body of the given content type can be recorded.
+func hasAuditFields(contentType string) bool {
+	return strings.Contains(contentType, "json") || strings.HasPrefix(contentType, "application/x-www-form-urlencoded")
+}
+
+// auditedBody is a request body whose beginning has been read by the Audit handler.
+type auditedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// auditFields extracts the fields from a JSON object or URL-encoded form body and redacts the specified fields.
+func auditFields(contentType string, body []byte, redacted map[string]bool) map[string]interface{} {
+	if len(body) == 0 {
+		return nil
+	}
+	fields := make(map[string]interface{})
+	switch {
+	case strings.Contains(contentType, "json"):
+		if json.Unmarshal(body, &fields) != nil {
+			return nil
+		}
+	case strings.HasPrefix(contentType, "application/x-www-form-urlencoded"):
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil
+		}
+		for name, v := range values {
+			if len(v) == 1 {
+				fields[name] = v[0]
+			} else {
+				fields[name] = v
+			}
+		}
+	default:
+		return nil
+	}
+	redactFields(fields, redacted)
+	return fields
+}
+
+// redactFields replaces the values of the redacted fields, including those in nested objects, with "***".
+func redactFields(fields map[string]interface{}, redacted map[string]bool) {
+	for name, value := range fields {
+		if redacted[strings.ToLower(name)] {
+			fields[name] = "***"
+			continue
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			redactFields(v, redacted)
+		case []interface{}:
+			for _, item := range v {
+				if m, ok := item.(map[string]interface{}); ok {
+					redactFields(m, redacted)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2015 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"testing"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+func TestAudit(t *testing.T) {
+	var entries []*AuditEntry
+	sink := AuditFunc(func(entry *AuditEntry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+
+	r := NewRouter()
+	r.Use(func(c *Context) {
+		c.Data["user"] = c.Request.Header.Get("X-User")
+		c.Next()
+	}, Audit(sink, AuditOptions{
+		Identity: func(c *Context) string { return c.Data["user"].(string) },
+		Redact:   []string{"Password"},
+		Exclude:  []string{"login"},
+	}))
+	r.Post("/users/<id>", func(c *Context) {
+		c.Response.WriteHeader(http.StatusCreated)
+	})
+	r.Post("/login", func() {}).Name = "login"
+	r.Get("/users/<id>", func() {})
+
+	tests := []struct {
+		// input
+		method      string
+		url         string
+		contentType string
+		body        string
+		// output
+		entry       string
+	}{
+		{"POST", "/users/1", "application/json", `{"name":"abc","password":"secret","profile":{"PASSWORD":"x"}}`,
+			`joe POST /users/1 [POST /users/<id>] {id:1,} {"name":"abc","password":"***","profile":{"PASSWORD":"***"}} 201`},
+		{"POST", "/users/2?x=y", "application/x-www-form-urlencoded", "name=abc&password=secret&tag=a&tag=b",
+			`joe POST /users/2?x=y [POST /users/<id>] {id:2,} {"name":"abc","password":"***","tag":["a","b"]} 201`},
+		{"POST", "/users/3", "text/plain", "abc",
+			`joe POST /users/3 [POST /users/<id>] {id:3,} null 201`},
+		{"POST", "/login", "application/json", `{"password":"secret"}`, ""},
+		{"GET", "/users/1", "", "", ""},
+		{"DELETE", "/posts", "", "", `joe DELETE /posts [] {} null 200`},
+	}
+
+	for _, tt := range tests {
+		entries = nil
+		req, _ := http.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", tt.contentType)
+		req.Header.Set("X-User", "joe")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+
+		entry := ""
+		if len(entries) > 0 {
+			e := entries[0]
+			if e.Time.IsZero() {
+				t.Errorf("Audit(%q, %q).Time is not set", tt.method, tt.url)
+			}
+			fields, _ := json.Marshal(e.Fields)
+			entry = fmt.Sprintf("%v %v %v [%v] %v %s %v", e.Identity, e.Method, e.URL, e.Route, fmtMap(e.Params), fields, e.Status)
+		}
+		if entry != tt.entry {
+			t.Errorf("Audit(%q, %q) = %v, want %v", tt.method, tt.url, entry, tt.entry)
+		}
+	}
+}
+
+func TestAuditMaxBodySize(t *testing.T) {
+	var entry *AuditEntry
+	sink := AuditFunc(func(e *AuditEntry) error {
+		entry = e
+		return nil
+	})
+	var received string
+	r := NewRouter()
+	r.Use(Audit(sink, AuditOptions{MaxBodySize: 10}))
+	r.Post("/users", func(c *Context) {
+		body, _ := ioutil.ReadAll(c.Request.Body)
+		received = string(body)
+	})
+
+	tests := []struct {
+		// input
+		contentType string
+		body        string
+		// output
+		fields      string
+	}{
+		{"application/json", `{"a":"b"}`, `{"a":"b"}`},
+		{"application/json", `{"a":"bcd"}`, "null"},
+		{"text/plain", "abcdefghijklmnop", "null"},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest("POST", "/users", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", tt.contentType)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+		if received != tt.body {
+			t.Errorf("Audit(%q) passed body %q, want %q", tt.body, received, tt.body)
+		}
+		if fields, _ := json.Marshal(entry.Fields); string(fields) != tt.fields {
+			t.Errorf("Audit(%q).Fields = %s, want %v", tt.body, fields, tt.fields)
+		}
+	}
+}
+
+func TestAuditWriter(t *testing.T) {
+	var buf bytes.Buffer
+	sink := AuditWriter(&buf)
+	sink.Audit(&AuditEntry{Method: "POST", URL: "/a"})
+	sink.Audit(&AuditEntry{Method: "PUT", URL: "/b"})
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], `"method":"PUT","url":"/b"`) {
+		t.Errorf("AuditWriter() wrote %q", buf.String())
+	}
+}
+
+func TestAuditError(t *testing.T) {
+	l := &LoggerMock{}
+	r := NewRouter()
+	r.Use(Audit(AuditFunc(func(entry *AuditEntry) error {
+		return errors.New("db down")
+	}), AuditOptions{Log: l.Error}))
+
+	req, _ := http.NewRequest("PUT", "/users", nil)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	if l.message != "audit failed: db down" {
+		t.Errorf("Expected log message %q, got %q", "audit failed: db down", l.message)
+	}
+}
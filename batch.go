@@ -0,0 +1,119 @@
+// Copyright 2015 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// BatchRequest is a sub-request of a batch request.
+type BatchRequest struct {
+	Method  string            `json:"method"`            // the HTTP method, defaulted to GET
+	Path    string            `json:"path"`              // the URL path, optionally with a query string
+	Headers map[string]string `json:"headers,omitempty"` // additional request headers
+	Body    json.RawMessage   `json:"body,omitempty"`    // the JSON request body
+}
+
+// batchRequestKey is the request context key marking the sub-requests dispatched by BatchHandler.
+type batchRequestKey struct{}
+
+// BatchResponse is the response of a sub-request of a batch request.
+type BatchResponse struct {
+	Status  int               `json:"status"`            // the response status code
+	Headers map[string]string `json:"headers,omitempty"` // the response headers
+	Body    interface{}       `json:"body,omitempty"`    // the response body, embedded as JSON if it is valid JSON content
+}
+
+// BatchHandler returns a handler that serves batch requests, which allows clients to reduce round trips.
+// A batch request contains a JSON array of sub-requests (see BatchRequest). Each sub-request is dispatched
+// through the given router in order, and a JSON array of their responses (see BatchResponse) is sent back.
+//
+// Sub-requests inherit the headers of the batch request, such as those for authentication. A batch request
+// with more than max sub-requests (if max is positive) or with an invalid body is rejected with an HTTPError
+// of the status http.StatusBadRequest. So is a sub-request that is itself a batch request, as nested batches
+// would multiply the number of dispatched requests. For example,
+//
+//   r := routing.NewRouter()
+//   r.Post("/batch", routing.BatchHandler(r, 20))
+func BatchHandler(router *Router, max int) Handler {
+	return func(c *Context) {
+		if c.Request.Context().Value(batchRequestKey{}) != nil {
+			c.Panic(http.StatusBadRequest, "a batch request cannot be nested")
+		}
+
+		var requests []BatchRequest
+		if err := json.NewDecoder(c.Request.Body).Decode(&requests); err != nil {
+			c.Panic(http.StatusBadRequest, "invalid batch request: " + err.Error())
+		}
+		if max > 0 && len(requests) > max {
+			c.Panic(http.StatusBadRequest, fmt.Sprintf("a batch request cannot contain more than %v requests", max))
+		}
+
+		responses := make([]BatchResponse, len(requests))
+		for i, request := range requests {
+			responses[i] = dispatchBatchRequest(router, c.Request, request)
+		}
+
+		c.Response.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(c.Response).Encode(responses); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// dispatchBatchRequest dispatches a sub-request through the router and returns its response.
+func dispatchBatchRequest(router *Router, parent *http.Request, request BatchRequest) BatchResponse {
+	method := strings.ToUpper(request.Method)
+	if method == "" {
+		method = "GET"
+	}
+	if !strings.HasPrefix(request.Path, "/") || strings.SplitN(request.Path, "?", 2)[0] == parent.URL.Path {
+		return BatchResponse{Status: http.StatusBadRequest, Body: "invalid path: " + request.Path}
+	}
+
+	req, err := http.NewRequest(method, request.Path, bytes.NewReader(request.Body))
+	if err != nil {
+		return BatchResponse{Status: http.StatusBadRequest, Body: err.Error()}
+	}
+	for name, values := range parent.Header {
+		if name != "Content-Type" && name != "Content-Length" && name != "Content-Encoding" {
+			req.Header[name] = values
+		}
+	}
+	if len(request.Body) > 0 {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range request.Headers {
+		req.Header.Set(name, value)
+	}
+	req = req.WithContext(context.WithValue(parent.Context(), batchRequestKey{}, true))
+	req.Host = parent.Host
+	req.RemoteAddr = parent.RemoteAddr
+	req.RequestURI = req.URL.RequestURI()
+
+	rw := &responseRecorder{header: make(http.Header)}
+	router.ServeHTTP(rw, req)
+
+	response := BatchResponse{Status: rw.Status()}
+	if len(rw.header) > 0 {
+		response.Headers = make(map[string]string)
+		for name := range rw.header {
+			response.Headers[name] = rw.header.Get(name)
+		}
+	}
+	if body := rw.body.Bytes(); len(body) > 0 {
+		if strings.Contains(rw.contentType, "json") && json.Valid(body) {
+			response.Body = json.RawMessage(body)
+		} else {
+			response.Body = string(body)
+		}
+	}
+	return response
+}
@@ -0,0 +1,89 @@
+// Copyright 2015 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"testing"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+func TestBatchHandler(t *testing.T) {
+	r := NewRouter()
+	r.Post("/batch", BatchHandler(r, 3))
+	r.Get("/users/<id>", func(c *Context) string {
+		c.Response.Header().Set("Content-Type", "application/json")
+		return fmt.Sprintf(`{"id":%q,"auth":%q,"q":%q}`, c.Params["id"], c.Request.Header.Get("Authorization"), c.Request.URL.Query().Get("q"))
+	})
+	r.Post("/users", func(c *Context) string {
+		data, _ := ioutil.ReadAll(c.Request.Body)
+		c.Response.WriteHeader(http.StatusCreated)
+		return c.Request.Header.Get("Content-Type") + " " + c.Request.Header.Get("X-Trace") + " " + string(data)
+	})
+	r.Use(NotFoundHandler())
+	r.Error(ErrorHandler(nil))
+
+	tests := []struct {
+		// input
+		body   string
+		// output
+		status int
+		result string
+	}{
+		{`[{"method":"GET","path":"/users/1?q=x"},{"method":"post","path":"/users","headers":{"X-Trace":"t1"},"body":{"name":"abc"}},{"path":"/posts"}]`, http.StatusOK,
+			`[{"status":200,"headers":{"Content-Type":"application/json"},"body":{"id":"1","auth":"token","q":"x"}},` +
+			`{"status":201,"body":"application/json t1 {\"name\":\"abc\"}"},` +
+			`{"status":404,"body":"Not Found"}]`},
+		{`[{"path":"/batch"},{"path":"users"}]`, http.StatusOK,
+			`[{"status":400,"body":"invalid path: /batch"},{"status":400,"body":"invalid path: users"}]`},
+		{`[]`, http.StatusOK, `[]`},
+		{`[{},{},{},{}]`, http.StatusBadRequest, "a batch request cannot contain more than 3 requests"},
+		{`{"path":"/users/1"}`, http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest("POST", "/batch", strings.NewReader(tt.body))
+		req.Header.Set("Authorization", "token")
+		req.Header.Set("Content-Type", "application/json")
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		if res.Code != tt.status {
+			t.Errorf("Batch(%q).status = %v, want %v", tt.body, res.Code, tt.status)
+		}
+		if result := strings.TrimSpace(res.Body.String()); tt.result != "" && result != tt.result {
+			t.Errorf("Batch(%q) = %v, want %v", tt.body, result, tt.result)
+		}
+	}
+}
+
+func TestBatchHandlerNested(t *testing.T) {
+	calls := 0
+	r := NewRouter()
+	r.MatrixParams = true
+	r.Post("/batch", BatchHandler(r, 3))
+	r.Get("/users", func() string {
+		calls++
+		return "ok"
+	})
+	r.Error(ErrorHandler(nil))
+
+	leaf := `{"path":"/users"}`
+	nested := `{"method":"POST","path":"/batch;a","body":[` + leaf + `,` + leaf + `,` + leaf + `]}`
+	body := `[` + nested + `,` + leaf + `]`
+	req, _ := http.NewRequest("POST", "/batch", strings.NewReader(body))
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+
+	result := `[{"status":400,"body":"a batch request cannot be nested"},{"status":200,"body":"ok"}]`
+	if strings.TrimSpace(res.Body.String()) != result {
+		t.Errorf("Batch(nested) = %v, want %v", strings.TrimSpace(res.Body.String()), result)
+	}
+	if calls != 1 {
+		t.Errorf("Batch(nested) called the leaf route %v times, want 1", calls)
+	}
+}
@@ -0,0 +1,263 @@
+// Copyright 2015 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// AttemptRecord is the record of the failed authentication attempts of a client.
+type AttemptRecord struct {
+	Failures    int       // the number of failures within the window
+	LastFailure time.Time // the time of the last failure
+	BannedUntil time.Time // the time until which the client is banned, or zero if the client is not banned
+}
+
+// AttemptStore stores the records of failed authentication attempts used by BruteForceGuard.
+// An implementation backed by a shared storage (e.g. Redis) can be used to protect a cluster of servers.
+// The methods may be called concurrently.
+type AttemptStore interface {
+	// Get returns the record of the given client key. A zero record is returned if there is none.
+	Get(key string) (AttemptRecord, error)
+	// Fail records a failure of the given client key and returns the updated record.
+	// Failures that happened more than window ago should not be counted.
+	Fail(key string, window time.Duration) (AttemptRecord, error)
+	// Ban bans the given client key until the given time and clears its failures.
+	Ban(key string, until time.Time) error
+	// Reset removes the record of the given client key.
+	Reset(key string) error
+}
+
+// BruteForceOptions defines the possible options for the BruteForceGuard handler.
+type BruteForceOptions struct {
+	// The function returning the key identifying the client, such as the login name being attacked.
+	// Requests with an empty key are not guarded. Defaults to the client IP address. Note that behind a reverse
+	// proxy, the client IP address is that of the proxy unless TrustProxy is true, which means all clients would
+	// share the same key and one attacker could get all of them banned.
+	Key          func(*Context) string
+	// Whether the default Key determines the client IP using the X-Forwarded-For and X-Real-IP headers. Only the
+	// rightmost X-Forwarded-For entry, which is appended by the proxy, is used, so that clients cannot evade the guard
+	// by sending a different X-Forwarded-For header with every request. This should only be enabled when the
+	// application is behind a trusted proxy which sets these headers, because otherwise the headers can be forged.
+	TrustProxy   bool
+	// The storage of the failure records. Defaults to a store kept in memory by the current process.
+	Store        AttemptStore
+	// The period during which failures are counted. Defaults to 15 minutes.
+	Window       time.Duration
+	// The number of failures allowed before requests are delayed.
+	FreeAttempts int
+	// The delay after the first failure beyond FreeAttempts. The delay doubles with every further failure.
+	// Defaults to one second.
+	Delay        time.Duration
+	// The maximum delay. Zero means no limit.
+	MaxDelay     time.Duration
+	// The number of failures after which the client is banned. Defaults to 10.
+	MaxFailures  int
+	// The period for which the client is banned. Defaults to 15 minutes.
+	BanDuration  time.Duration
+}
+
+// Authentication outcomes reported via Context.
+const (
+	authUnknown = iota
+	authSucceeded
+	authFailed
+)
+
+// BruteForceGuard returns a handler that protects the subsequent handlers against brute-force attacks on credentials.
+// The handlers report the outcome of authentication by calling Context.AuthFailed() or Context.AuthSucceeded().
+// Requests from a client that has failed repeatedly are delayed, with the delay growing with every failure.
+// After too many failures, the client is banned for a while, and its requests are rejected with an HTTPError
+// of the status http.StatusTooManyRequests (429) and a Retry-After header. A successful authentication clears
+// the failures of the client. For example,
+//
+//   r.Post("/login", routing.BruteForceGuard(routing.BruteForceOptions{}), func(c *routing.Context) {
+//       if !checkPassword(c.Request) {
+//           c.AuthFailed()
+//           c.Panic(http.StatusUnauthorized)
+//       }
+//       c.AuthSucceeded()
+//       // ...
+//   })
+//
+// Every attempt is counted as a failure before it is handled, and the count is cleared when the handlers report
+// a successful authentication. Concurrent attempts therefore count towards MaxFailures while they are in progress,
+// and attempts whose handlers report no outcome remain counted as failures.
+//
+// BruteForceGuard panics with the error returned by the store, which results in an internal server error.
+func BruteForceGuard(opts BruteForceOptions) Handler {
+	if opts.Key == nil {
+		opts.Key = func(c *Context) string {
+			return clientIP(c.Request, opts.TrustProxy)
+		}
+	}
+	if opts.Store == nil {
+		opts.Store = NewMemoryAttemptStore()
+	}
+	if opts.Window <= 0 {
+		opts.Window = 15 * time.Minute
+	}
+	if opts.Delay <= 0 {
+		opts.Delay = time.Second
+	}
+	if opts.MaxFailures <= 0 {
+		opts.MaxFailures = 10
+	}
+	if opts.BanDuration <= 0 {
+		opts.BanDuration = 15 * time.Minute
+	}
+
+	return func(c *Context) {
+		key := opts.Key(c)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		opts.checkBan(c, key)
+		record, err := opts.Store.Fail(key, opts.Window)
+		if err != nil {
+			panic(err)
+		}
+		if record.Failures > opts.MaxFailures {
+			// too many attempts are in progress concurrently
+			opts.ban(key)
+			rejectBanned(c, opts.BanDuration)
+		}
+		if delay := opts.delay(record.Failures - 1); delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-c.Request.Context().Done():
+				return
+			}
+			// the client may have been banned by a concurrent attempt during the delay
+			opts.checkBan(c, key)
+		}
+
+		c.auth = authUnknown
+		c.Next()
+
+		switch c.auth {
+		case authFailed:
+			if record.Failures >= opts.MaxFailures {
+				opts.ban(key)
+			}
+		case authSucceeded:
+			if err := opts.Store.Reset(key); err != nil {
+				panic(err)
+			}
+		}
+	}
+}
+
+// checkBan rejects the request if the client with the given key is banned.
+func (opts *BruteForceOptions) checkBan(c *Context, key string) {
+	record, err := opts.Store.Get(key)
+	if err != nil {
+		panic(err)
+	}
+	if now := time.Now(); now.Before(record.BannedUntil) {
+		rejectBanned(c, record.BannedUntil.Sub(now))
+	}
+}
+
+// ban bans the client with the given key for BanDuration.
+func (opts *BruteForceOptions) ban(key string) {
+	if err := opts.Store.Ban(key, time.Now().Add(opts.BanDuration)); err != nil {
+		panic(err)
+	}
+}
+
+// rejectBanned rejects the request of a banned client, telling it to retry after the given period.
+func rejectBanned(c *Context, retry time.Duration) {
+	c.Response.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retry.Seconds()))))
+	c.Panic(http.StatusTooManyRequests)
+}
+
+// delay returns the delay to be applied to a request from a client with the given number of failures.
+func (opts *BruteForceOptions) delay(failures int) time.Duration {
+	n := failures - opts.FreeAttempts
+	if n <= 0 {
+		return 0
+	}
+	delay := opts.Delay
+	for i := 1; i < n && (opts.MaxDelay <= 0 || delay < opts.MaxDelay) && delay < math.MaxInt64 / 2; i++ {
+		delay *= 2
+	}
+	if opts.MaxDelay > 0 && delay > opts.MaxDelay {
+		delay = opts.MaxDelay
+	}
+	return delay
+}
+
+// AuthFailed reports that the current request failed to authenticate. It is used by BruteForceGuard.
+func (c *Context) AuthFailed() {
+	c.auth = authFailed
+}
+
+// AuthSucceeded reports that the current request authenticated successfully. It is used by BruteForceGuard.
+func (c *Context) AuthSucceeded() {
+	c.auth = authSucceeded
+}
+
+// memoryAttemptStore is an AttemptStore that keeps the records in memory.
+type memoryAttemptStore struct {
+	mu      sync.Mutex
+	records map[string]AttemptRecord
+	writes  int
+}
+
+// NewMemoryAttemptStore creates an AttemptStore that keeps the records in the memory of the current process.
+func NewMemoryAttemptStore() AttemptStore {
+	return &memoryAttemptStore{records: make(map[string]AttemptRecord)}
+}
+
+func (s *memoryAttemptStore) Get(key string) (AttemptRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.records[key], nil
+}
+
+func (s *memoryAttemptStore) Fail(key string, window time.Duration) (AttemptRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.writes++; s.writes % 1000 == 0 {
+		// remove the records that are no longer effective
+		for k, record := range s.records {
+			if now.Sub(record.LastFailure) >= window && now.After(record.BannedUntil) {
+				delete(s.records, k)
+			}
+		}
+	}
+
+	record := s.records[key]
+	if now.Sub(record.LastFailure) >= window {
+		record.Failures = 0
+	}
+	record.Failures++
+	record.LastFailure = now
+	s.records[key] = record
+	return record, nil
+}
+
+func (s *memoryAttemptStore) Ban(key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[key] = AttemptRecord{BannedUntil: until}
+	return nil
+}
+
+func (s *memoryAttemptStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
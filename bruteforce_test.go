@@ -0,0 +1,179 @@
+// Copyright 2015 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"testing"
+	"net/http"
+	"net/http/httptest"
+	"time"
+)
+
+func TestBruteForceDelay(t *testing.T) {
+	opts := &BruteForceOptions{FreeAttempts: 2, Delay: time.Second, MaxDelay: 5 * time.Second}
+	tests := []struct {
+		failures int
+		delay    time.Duration
+	}{
+		{0, 0},
+		{2, 0},
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{5, 4 * time.Second},
+		{6, 5 * time.Second},
+		{100, 5 * time.Second},
+	}
+	for _, tt := range tests {
+		if delay := opts.delay(tt.failures); delay != tt.delay {
+			t.Errorf("delay(%v) = %v, want %v", tt.failures, delay, tt.delay)
+		}
+	}
+}
+
+func TestMemoryAttemptStore(t *testing.T) {
+	s := NewMemoryAttemptStore()
+	if record, _ := s.Get("a"); record.Failures != 0 {
+		t.Errorf("Get() = %v, want no failures", record.Failures)
+	}
+	s.Fail("a", time.Hour)
+	if record, _ := s.Fail("a", time.Hour); record.Failures != 2 {
+		t.Errorf("Fail() = %v, want 2", record.Failures)
+	}
+	if record, _ := s.Fail("a", 0); record.Failures != 1 {
+		t.Errorf("Fail() after the window = %v, want 1", record.Failures)
+	}
+	until := time.Now().Add(time.Hour)
+	s.Ban("a", until)
+	if record, _ := s.Get("a"); record.Failures != 0 || !record.BannedUntil.Equal(until) {
+		t.Errorf("Get() after Ban() = %v, want a ban without failures", record)
+	}
+	s.Reset("a")
+	if record, _ := s.Get("a"); !record.BannedUntil.IsZero() {
+		t.Errorf("Get() after Reset() = %v, want a zero record", record)
+	}
+}
+
+func TestBruteForceGuard(t *testing.T) {
+	r := NewRouter()
+	r.Post("/login", BruteForceGuard(BruteForceOptions{
+		Key: func(c *Context) string {
+			return c.Request.FormValue("user")
+		},
+		FreeAttempts: 1,
+		Delay:        20 * time.Millisecond,
+		MaxFailures:  3,
+		BanDuration:  time.Hour,
+	}), func(c *Context) {
+		if c.Request.FormValue("password") != "secret" {
+			c.AuthFailed()
+			c.Panic(http.StatusUnauthorized)
+		}
+		c.AuthSucceeded()
+	})
+	r.Error(ErrorHandler(nil))
+
+	tests := []struct {
+		// input
+		user     string
+		password string
+		// output
+		status   int
+		delayed  bool
+	}{
+		{"alice", "wrong", http.StatusUnauthorized, false},
+		{"alice", "secret", http.StatusOK, false},
+		{"alice", "wrong", http.StatusUnauthorized, false},
+		{"alice", "wrong", http.StatusUnauthorized, false},
+		{"alice", "wrong", http.StatusUnauthorized, true},
+		{"bob", "wrong", http.StatusUnauthorized, false},
+		{"alice", "secret", http.StatusTooManyRequests, false},
+		{"", "wrong", http.StatusUnauthorized, false},
+	}
+
+	for i, tt := range tests {
+		req, _ := http.NewRequest("POST", "/login?user=" + tt.user + "&password=" + tt.password, nil)
+		res := httptest.NewRecorder()
+		start := time.Now()
+		r.ServeHTTP(res, req)
+		if res.Code != tt.status {
+			t.Errorf("%v. BruteForceGuard(%q, %q) = %v, want %v", i, tt.user, tt.password, res.Code, tt.status)
+		}
+		if delayed := time.Now().Sub(start) >= 20 * time.Millisecond; delayed != tt.delayed {
+			t.Errorf("%v. BruteForceGuard(%q, %q) delayed = %v, want %v", i, tt.user, tt.password, delayed, tt.delayed)
+		}
+		if tt.status == http.StatusTooManyRequests && res.Header().Get("Retry-After") != "3600" {
+			t.Errorf("%v. BruteForceGuard(%q, %q).Retry-After = %q, want %q", i, tt.user, tt.password, res.Header().Get("Retry-After"), "3600")
+		}
+	}
+}
+
+func TestBruteForceGuardTrustProxy(t *testing.T) {
+	r := NewRouter()
+	r.Post("/login", BruteForceGuard(BruteForceOptions{TrustProxy: true, MaxFailures: 1, BanDuration: time.Hour}), func(c *Context) {
+		c.AuthFailed()
+		c.Panic(http.StatusUnauthorized)
+	})
+	r.Error(ErrorHandler(nil))
+
+	tests := []struct {
+		forwarded string
+		status    int
+	}{
+		{"1.2.3.4", http.StatusUnauthorized},
+		{"1.2.3.4", http.StatusTooManyRequests},
+		{"9.9.9.9, 1.2.3.4", http.StatusTooManyRequests},
+		{"5.6.7.8", http.StatusUnauthorized},
+	}
+	for i, tt := range tests {
+		req, _ := http.NewRequest("POST", "/login", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		req.Header.Set("X-Forwarded-For", tt.forwarded)
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		if res.Code != tt.status {
+			t.Errorf("%v. BruteForceGuard(%q) = %v, want %v", i, tt.forwarded, res.Code, tt.status)
+		}
+	}
+}
+
+func TestBruteForceGuardConcurrent(t *testing.T) {
+	release := make(chan bool)
+	r := NewRouter()
+	r.Post("/login", BruteForceGuard(BruteForceOptions{FreeAttempts: 10, MaxFailures: 2, BanDuration: time.Hour}), func(c *Context) {
+		<-release
+		c.AuthFailed()
+		c.Panic(http.StatusUnauthorized)
+	})
+	r.Error(ErrorHandler(nil))
+
+	codes := make(chan int)
+	for i := 0; i < 5; i++ {
+		go func() {
+			req, _ := http.NewRequest("POST", "/login", nil)
+			req.RemoteAddr = "10.0.0.1:1234"
+			res := httptest.NewRecorder()
+			r.ServeHTTP(res, req)
+			codes <- res.Code
+		}()
+	}
+
+	// the attempts beyond MaxFailures are rejected while the others are in progress
+	for i := 0; i < 3; i++ {
+		select {
+		case code := <-codes:
+			if code != http.StatusTooManyRequests {
+				t.Errorf("BruteForceGuard() = %v, want %v", code, http.StatusTooManyRequests)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("BruteForceGuard() let more than MaxFailures concurrent attempts through")
+		}
+	}
+	close(release)
+	for i := 0; i < 2; i++ {
+		if code := <-codes; code != http.StatusUnauthorized {
+			t.Errorf("BruteForceGuard() = %v, want %v", code, http.StatusUnauthorized)
+		}
+	}
+}
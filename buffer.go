@@ -0,0 +1,223 @@
+// Copyright 2015 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// ResponseBuffer is a response writer that holds the response status and body until the handler chain completes.
+// Response content is kept in memory until its size exceeds a limit, after which it is spilled to a temporary file.
+//
+// Because nothing is sent to the client before the chain completes, handlers may still set headers and
+// the status code after the response content is written, and they may transform the content by calling Transform().
+type ResponseBuffer struct {
+	http.ResponseWriter
+	status    int
+	limit     int64
+	body      *bufferStorage
+	discarded bool
+}
+
+// BufferResponse returns a handler that buffers the response of the subsequent handlers using a ResponseBuffer.
+// The response content is kept in memory until its size exceeds limit bytes, after which it is spilled to a temporary
+// file. If limit is not positive, the content is always kept in memory. When the handler chain completes,
+// the Content-Length header is set (if not set yet) and the buffered response is sent. The Content-Length header
+// is not set for responses to HEAD requests and for responses with the status 1xx, 204 or 304, which have no body.
+//
+// The current ResponseBuffer can be obtained by calling Context.Buffer(). Routes that stream their responses
+// should call Context.DiscardBuffer() to send the response directly.
+func BufferResponse(limit int64) Handler {
+	return func(c *Context) {
+		if c.buffer != nil {
+			c.Next()
+			return
+		}
+
+		rb := &ResponseBuffer{ResponseWriter: c.Response, limit: limit, body: &bufferStorage{limit: limit}}
+		c.Response = rb
+		c.buffer = rb
+		defer func() {
+			c.Response = rb.ResponseWriter
+			c.buffer = nil
+			rb.body.close()
+		}()
+
+		c.Next()
+
+		if err := rb.flush(c.Request.Method); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Buffer returns the ResponseBuffer that holds the current response, or nil if the response is not being buffered.
+func (c *Context) Buffer() *ResponseBuffer {
+	if c.buffer == nil || c.buffer.discarded {
+		return nil
+	}
+	return c.buffer
+}
+
+// DiscardBuffer stops buffering the current response. The content buffered so far is sent immediately
+// and the content written afterwards is sent directly. This is useful for routes that stream their responses.
+// Nothing is done if the response is not being buffered.
+func (c *Context) DiscardBuffer() {
+	if c.buffer != nil {
+		c.buffer.discard()
+	}
+}
+
+// Write writes the data to the buffer.
+func (rb *ResponseBuffer) Write(p []byte) (int, error) {
+	if rb.discarded {
+		return rb.ResponseWriter.Write(p)
+	}
+	return rb.body.Write(p)
+}
+
+// WriteHeader records the status code to be sent. Unlike http.ResponseWriter, the last call takes effect.
+func (rb *ResponseBuffer) WriteHeader(status int) {
+	if rb.discarded {
+		rb.ResponseWriter.WriteHeader(status)
+		return
+	}
+	rb.status = status
+}
+
+// Status returns the recorded status code, or http.StatusOK if no status code has been recorded.
+func (rb *ResponseBuffer) Status() int {
+	if rb.status == 0 {
+		return http.StatusOK
+	}
+	return rb.status
+}
+
+// Len returns the size of the buffered content in bytes.
+func (rb *ResponseBuffer) Len() int64 {
+	return rb.body.size
+}
+
+// Transform replaces the buffered content with the output of the given function, which reads the current
+// content from r and writes the new content to w. The buffered content is not changed if the function fails.
+func (rb *ResponseBuffer) Transform(f func(r io.Reader, w io.Writer) error) error {
+	r, err := rb.body.reader()
+	if err != nil {
+		return err
+	}
+	body := &bufferStorage{limit: rb.limit}
+	if err := f(r, body); err != nil {
+		body.close()
+		return err
+	}
+	rb.body.close()
+	rb.body = body
+	return nil
+}
+
+// discard sends the status and the content buffered so far, and turns the buffer into a pass-through writer.
+func (rb *ResponseBuffer) discard() {
+	if rb.discarded {
+		return
+	}
+	rb.discarded = true
+	if rb.status != 0 {
+		rb.ResponseWriter.WriteHeader(rb.status)
+	}
+	if r, err := rb.body.reader(); err == nil {
+		io.Copy(rb.ResponseWriter, r)
+	}
+	rb.body.close()
+	rb.body = &bufferStorage{}
+}
+
+// flush sends the buffered response of a request with the given method.
+func (rb *ResponseBuffer) flush(method string) error {
+	if rb.discarded {
+		return nil
+	}
+	status := rb.Status()
+	header := rb.ResponseWriter.Header()
+	if bodyAllowed(method, status) && header.Get("Content-Length") == "" && header.Get("Transfer-Encoding") == "" {
+		header.Set("Content-Length", strconv.FormatInt(rb.body.size, 10))
+	}
+	rb.ResponseWriter.WriteHeader(status)
+	r, err := rb.body.reader()
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(rb.ResponseWriter, r)
+	return err
+}
+
+// bodyAllowed checks if the response to a request with the given method and the given status has a body whose
+// length should be declared. Responses to HEAD requests declare the length of the body that GET would return,
+// which is unknown here.
+func bodyAllowed(method string, status int) bool {
+	return method != "HEAD" && status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
+}
+
+// bufferStorage keeps content in memory until its size exceeds the limit, after which it uses a temporary file.
+type bufferStorage struct {
+	limit int64
+	size  int64
+	mem   bytes.Buffer
+	file  *os.File
+}
+
+func (s *bufferStorage) Write(p []byte) (int, error) {
+	if s.file == nil && s.limit > 0 && s.size + int64(len(p)) > s.limit {
+		file, err := ioutil.TempFile("", "ozzo-routing")
+		if err != nil {
+			return 0, err
+		}
+		if _, err := file.Write(s.mem.Bytes()); err != nil {
+			file.Close()
+			os.Remove(file.Name())
+			return 0, err
+		}
+		s.file = file
+		s.mem.Reset()
+	}
+
+	var (
+		n   int
+		err error
+	)
+	if s.file != nil {
+		n, err = s.file.Write(p)
+	} else {
+		n, err = s.mem.Write(p)
+	}
+	s.size += int64(n)
+	return n, err
+}
+
+// reader returns a reader of the stored content from the beginning.
+func (s *bufferStorage) reader() (io.Reader, error) {
+	if s.file == nil {
+		return bytes.NewReader(s.mem.Bytes()), nil
+	}
+	if _, err := s.file.Seek(0, 0); err != nil {
+		return nil, err
+	}
+	return s.file, nil
+}
+
+// close releases the resources held by the storage.
+func (s *bufferStorage) close() {
+	if s.file != nil {
+		s.file.Close()
+		os.Remove(s.file.Name())
+		s.file = nil
+	}
+	s.mem.Reset()
+	s.size = 0
+}
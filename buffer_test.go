@@ -0,0 +1,112 @@
+// Copyright 2015 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"testing"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+)
+
+func TestBufferResponse(t *testing.T) {
+	r := NewRouter()
+	r.Use(BufferResponse(4), func(c *Context) {
+		c.Next()
+		// headers and status can still be changed after the content is written
+		c.Response.Header().Set("X-Length", c.Response.Header().Get("Content-Length"))
+		if c.Buffer() != nil && c.Buffer().Status() == http.StatusOK {
+			c.Response.Header().Set("X-Buffered", "yes")
+			c.Response.WriteHeader(http.StatusAccepted)
+		}
+	})
+	r.To("GET,HEAD /short", func() string {
+		return "abc"
+	})
+	r.Get("/long", func() string {
+		return "abcdefghij"
+	})
+	r.Get("/upper", func(c *Context) {
+		c.Response.Write([]byte("abcdefghij"))
+		c.Buffer().Transform(func(r io.Reader, w io.Writer) error {
+			data, err := ioutil.ReadAll(r)
+			if err == nil {
+				_, err = w.Write(bytes.ToUpper(data))
+			}
+			return err
+		})
+	})
+	r.Get("/empty", func(c *Context) {
+		c.Response.WriteHeader(http.StatusNoContent)
+	})
+	r.Get("/cached", func(c *Context) {
+		c.Response.WriteHeader(http.StatusNotModified)
+	})
+	r.Get("/stream", func(c *Context) {
+		c.Response.Write([]byte("abc"))
+		c.DiscardBuffer()
+		c.Response.Write([]byte("def"))
+	})
+
+	tests := []struct {
+		// input
+		method   string
+		path     string
+		// output
+		status   int
+		body     string
+		length   string
+		buffered string
+	}{
+		{"GET", "/short", http.StatusAccepted, "abc", "3", "yes"},
+		{"GET", "/long", http.StatusAccepted, "abcdefghij", "10", "yes"},
+		{"GET", "/upper", http.StatusAccepted, "ABCDEFGHIJ", "10", "yes"},
+		{"GET", "/empty", http.StatusNoContent, "", "", ""},
+		{"GET", "/cached", http.StatusNotModified, "", "", ""},
+		{"HEAD", "/short", http.StatusAccepted, "abc", "", "yes"},
+		{"GET", "/stream", http.StatusOK, "abcdef", "", ""},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest(tt.method, tt.path, nil)
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		if res.Code != tt.status {
+			t.Errorf("Dispatch(%q).status = %v, want %v", tt.path, res.Code, tt.status)
+		}
+		if res.Body.String() != tt.body {
+			t.Errorf("Dispatch(%q).body = %q, want %q", tt.path, res.Body.String(), tt.body)
+		}
+		if res.Header().Get("Content-Length") != tt.length {
+			t.Errorf("Dispatch(%q).Content-Length = %q, want %q", tt.path, res.Header().Get("Content-Length"), tt.length)
+		}
+		if res.Header().Get("X-Buffered") != tt.buffered {
+			t.Errorf("Dispatch(%q).X-Buffered = %q, want %q", tt.path, res.Header().Get("X-Buffered"), tt.buffered)
+		}
+	}
+}
+
+func TestBufferStorage(t *testing.T) {
+	s := &bufferStorage{limit: 4}
+	s.Write([]byte("abc"))
+	if s.file != nil {
+		t.Errorf("bufferStorage spilled to file before exceeding limit")
+	}
+	s.Write([]byte("def"))
+	if s.file == nil {
+		t.Errorf("bufferStorage did not spill to file after exceeding limit")
+	}
+	name := s.file.Name()
+	r, _ := s.reader()
+	if data, _ := ioutil.ReadAll(r); string(data) != "abcdef" || s.size != 6 {
+		t.Errorf("bufferStorage content = %q (%v), want %q (%v)", data, s.size, "abcdef", 6)
+	}
+	s.close()
+	if _, err := ioutil.ReadFile(name); err == nil {
+		t.Errorf("bufferStorage did not remove temporary file %q", name)
+	}
+}
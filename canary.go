@@ -0,0 +1,150 @@
+// Copyright 2015 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"hash/fnv"
+	"math/rand"
+)
+
+// CanaryVariant is one of the handler sets among which Canary distributes requests.
+type CanaryVariant struct {
+	Name     string    // the name of the variant, which is stored in Context.Data["canary"] when the variant is selected
+	Weight   int       // the relative share of the traffic sent to the variant when no selector chooses a variant
+	Handlers []Handler // the handlers to be called when the variant is selected
+}
+
+// CanarySelector chooses the variant to handle the current request. It returns the index of the chosen variant,
+// or -1 if it does not make a choice.
+type CanarySelector func(c *Context, variants []CanaryVariant) int
+
+// Canary returns a handler that sends each request to the handlers of one of the given variants.
+// This allows new handler implementations to be canaried in-process alongside the existing ones.
+//
+// The selectors are consulted in order and the first choice is taken. If no selector makes a choice,
+// a variant is picked randomly according to the variant weights. For example, the following code sends
+// 5% of the requests to the new implementation, unless the client asks for a variant explicitly:
+//
+//   r.Get("/users", routing.Canary([]routing.CanaryVariant{
+//       {"stable", 95, []routing.Handler{listUsers}},
+//       {"canary", 5, []routing.Handler{listUsersV2}},
+//   }, routing.HeaderSelector("X-Variant")))
+//
+// The handlers of a variant are called like the handlers of a route: a handler may call Context.Next()
+// to pass the control to the next handler of the variant, and after the last one, to the next handler
+// of the route.
+func Canary(variants []CanaryVariant, selectors ...CanarySelector) Handler {
+	total := 0
+	for _, variant := range variants {
+		validateHandlers(variant.Handlers)
+		if variant.Weight < 0 {
+			panic("the weight of a canary variant cannot be negative")
+		}
+		total += variant.Weight
+	}
+	if len(variants) == 0 {
+		panic("canary variants are required")
+	}
+
+	return func(c *Context) {
+		index := -1
+		for _, selector := range selectors {
+			if index = selector(c, variants); index >= 0 && index < len(variants) {
+				break
+			}
+			index = -1
+		}
+		if index < 0 {
+			index = pickVariant(variants, total, uint32(rand.Int63()))
+		}
+
+		c.Data["canary"] = variants[index].Name
+		callHandlers(c, variants[index].Handlers)
+	}
+}
+
+// HeaderSelector returns a CanarySelector that chooses the variant whose name equals the value of the given request header.
+func HeaderSelector(header string) CanarySelector {
+	return func(c *Context, variants []CanaryVariant) int {
+		return findVariant(variants, c.Request.Header.Get(header))
+	}
+}
+
+// CookieSelector returns a CanarySelector that chooses the variant whose name equals the value of the given cookie.
+func CookieSelector(name string) CanarySelector {
+	return func(c *Context, variants []CanaryVariant) int {
+		if cookie, err := c.Request.Cookie(name); err == nil {
+			return findVariant(variants, cookie.Value)
+		}
+		return -1
+	}
+}
+
+// HashSelector returns a CanarySelector that chooses a variant according to the variant weights and the hash of
+// the key returned by the given function, such as the ID of the current user. This ensures the same key is always
+// sent to the same variant. No choice is made if the key is empty.
+func HashSelector(key func(*Context) string) CanarySelector {
+	return func(c *Context, variants []CanaryVariant) int {
+		k := key(c)
+		if k == "" {
+			return -1
+		}
+		h := fnv.New32a()
+		h.Write([]byte(k))
+		total := 0
+		for _, variant := range variants {
+			total += variant.Weight
+		}
+		return pickVariant(variants, total, h.Sum32())
+	}
+}
+
+// pickVariant picks a variant according to the weights using the given number as the source of randomness.
+func pickVariant(variants []CanaryVariant, total int, n uint32) int {
+	if total <= 0 {
+		return 0
+	}
+	point := int(n % uint32(total))
+	for i, variant := range variants {
+		if point < variant.Weight {
+			return i
+		}
+		point -= variant.Weight
+	}
+	return len(variants) - 1
+}
+
+func findVariant(variants []CanaryVariant, name string) int {
+	if name == "" {
+		return -1
+	}
+	for i, variant := range variants {
+		if variant.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// callHandlers calls the given handlers one after another like the handlers of a route.
+// After the last handler, Context.Next() passes the control to the handler following the current one.
+func callHandlers(c *Context, handlers []Handler) {
+	index := 0
+	oldNext := c.Next
+
+	c.Next = func() {
+		if index < len(handlers) && c.Error == nil {
+			handler := handlers[index]
+			index++
+			callHandler(c, handler)
+		} else {
+			index = len(handlers)
+			c.Next = oldNext
+			oldNext()
+		}
+	}
+
+	c.Next()
+}
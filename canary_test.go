@@ -0,0 +1,97 @@
+// Copyright 2015 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"testing"
+	"net/http"
+	"net/http/httptest"
+)
+
+func TestPickVariant(t *testing.T) {
+	variants := []CanaryVariant{{"a", 95, nil}, {"b", 5, nil}, {"c", 0, nil}}
+	tests := []struct {
+		n     uint32
+		index int
+	}{
+		{0, 0},
+		{94, 0},
+		{95, 1},
+		{99, 1},
+		{100, 0},
+		{195, 1},
+	}
+	for _, tt := range tests {
+		if index := pickVariant(variants, 100, tt.n); index != tt.index {
+			t.Errorf("pickVariant(%v) = %v, want %v", tt.n, index, tt.index)
+		}
+	}
+	if index := pickVariant(variants, 0, 10); index != 0 {
+		t.Errorf("pickVariant() with zero weights = %v, want 0", index)
+	}
+}
+
+func TestCanary(t *testing.T) {
+	r := NewRouter()
+	r.Get("/users", handleNext("users"), Canary([]CanaryVariant{
+		{"stable", 1, []Handler{handle("stable")}},
+		{"canary", 0, []Handler{handleNext("canary1"), handleNext("canary2")}},
+	}, HeaderSelector("X-Variant"), CookieSelector("variant")))
+	r.Get("/users", handle("next"))
+	r.Get("/posts", Canary([]CanaryVariant{
+		{"a", 50, []Handler{handle("a")}},
+		{"b", 50, []Handler{handle("b")}},
+	}, HashSelector(func(c *Context) string { return c.Request.Header.Get("X-User") })))
+
+	tests := []struct {
+		// input
+		path    string
+		header  string
+		cookie  string
+		user    string
+		// output
+		result  string
+	}{
+		{"/users", "", "", "", "<users<stable>users>"},
+		{"/users", "stable", "", "", "<users<stable>users>"},
+		{"/users", "unknown", "", "", "<users<stable>users>"},
+		{"/users", "canary", "", "", "<users<canary1<canary2<next>canary2>canary1>users>"},
+		{"/users", "", "canary", "", "<users<canary1<canary2<next>canary2>canary1>users>"},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", tt.path, nil)
+		req.Header.Set("X-Variant", tt.header)
+		if tt.cookie != "" {
+			req.AddCookie(&http.Cookie{Name: "variant", Value: tt.cookie})
+		}
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		if res.Body.String() != tt.result {
+			t.Errorf("Dispatch(%q, %q, %q) = %q, want %q", tt.path, tt.header, tt.cookie, res.Body.String(), tt.result)
+		}
+	}
+
+	// the same user is always sent to the same variant
+	results := make(map[string]bool)
+	for _, user := range []string{"u1", "u2", "u3", "u4", "u5", "u6", "u7", "u8"} {
+		var first string
+		for i := 0; i < 3; i++ {
+			req, _ := http.NewRequest("GET", "/posts", nil)
+			req.Header.Set("X-User", user)
+			res := httptest.NewRecorder()
+			r.ServeHTTP(res, req)
+			if i == 0 {
+				first = res.Body.String()
+			} else if res.Body.String() != first {
+				t.Errorf("HashSelector() sent user %q to %q and %q", user, first, res.Body.String())
+			}
+		}
+		results[first] = true
+	}
+	if !results["<a>"] || !results["<b>"] {
+		t.Errorf("HashSelector() did not distribute users among variants: %v", results)
+	}
+}
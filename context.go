@@ -5,7 +5,11 @@
 package routing
 
 import (
+	"fmt"
 	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
 	"github.com/go-ozzo/ozzo-di"
 )
 
@@ -31,12 +35,20 @@ type Context struct {
 
 	Request   *http.Request          // the current HTTP request
 	Response  http.ResponseWriter    // the response writer
+	Route     *Route                 // the route whose handlers are being called, or were called last
 	Params    map[string]string      // the URL parameter values of the matching route(s)
+	Matrix    map[string]url.Values  // the matrix parameters keyed by URL path segments (see Router.MatrixParams)
 	Data      map[string]interface{} // the data shared by applicable handlers
 	Error     interface{}            // the error recovered from panic
 
 	Next      func()                 // Next invokes the next handler on the current route
 	NextRoute func()                 // NextRoute invokes the first handler on the next matching route
+
+	buffer    *ResponseBuffer        // the buffer holding the response (see BufferResponse)
+	router    *Router                // the router serving the request
+	auth      int                    // the outcome of authentication (see BruteForceGuard)
+	debug     *DebugInfo             // the diagnostic information of the error (see Router.Debug)
+	debugging bool                   // whether the router dispatching the request is in debug mode
 }
 
 // NewContext creates a new Context with the given response and request information.
@@ -44,6 +56,7 @@ func NewContext(res http.ResponseWriter, req *http.Request) *Context {
 	c := &Context{
 		Container: di.NewContainer(),
 		Params: make(map[string]string),
+		Matrix: make(map[string]url.Values),
 		Request: req,
 		Response: res,
 		Next: func() {},
@@ -60,3 +73,60 @@ func NewContext(res http.ResponseWriter, req *http.Request) *Context {
 func (c *Context) Panic(status int, message ...string) {
 	panic(NewHTTPError(status, message...))
 }
+
+// ReadParams populates the fields of the struct pointed to by data with the URL parameter values in Context.Params.
+// A field is populated with the parameter named by its "param" tag, or with the parameter whose name equals
+// the field name case-insensitively if the field has no such tag. Fields tagged with `param:"-"` and fields
+// without a matching parameter are left unchanged. The parameter values are converted into the field types,
+// which can be strings, integers, floating point numbers, booleans, or pointers to these types. For example,
+//
+//   var params struct {
+//       ID   int    `param:"id"`
+//       Slug string `param:"slug"`
+//   }
+//   if err := c.ReadParams(&params); err != nil {
+//       panic(err)
+//   }
+//
+// If a parameter value cannot be converted, an HTTPError of the status http.StatusBadRequest (400) is returned.
+func (c *Context) ReadParams(data interface{}) error {
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("ReadParams requires a pointer to a struct, got %T", data)
+	}
+
+	v = v.Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := field.Tag.Get("param")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = findParam(c.Params, field.Name)
+		}
+		value, ok := c.Params[name]
+		if !ok {
+			continue
+		}
+		if err := setFieldValue(v.Field(i), value); err != nil {
+			return NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid value for %q: %v", name, err))
+		}
+	}
+	return nil
+}
+
+// findParam returns the name of the parameter that equals the given name case-insensitively,
+// or an empty string if there is no such parameter.
+func findParam(params map[string]string, name string) string {
+	for n := range params {
+		if strings.EqualFold(n, name) {
+			return n
+		}
+	}
+	return ""
+}
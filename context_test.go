@@ -22,3 +22,34 @@ func TestContextPanic(t *testing.T) {
 	c := NewContext(nil, nil)
 	c.Panic(http.StatusNotFound)
 }
+
+func TestContextReadParams(t *testing.T) {
+	type params struct {
+		ID      int      `param:"id"`
+		Slug    string   `param:"slug"`
+		Page    *uint
+		Draft   bool     `param:"-"`
+		Score   float64
+		private string
+	}
+
+	c := NewContext(nil, nil)
+	c.Params = map[string]string{"id": "12", "slug": "hello", "page": "3", "draft": "true", "private": "x", "other": "y"}
+	p := params{Score: 1.5}
+	if err := c.ReadParams(&p); err != nil {
+		t.Errorf("ReadParams() returned error: %v", err)
+	}
+	if p.ID != 12 || p.Slug != "hello" || p.Page == nil || *p.Page != 3 || p.Draft || p.Score != 1.5 || p.private != "" {
+		t.Errorf("ReadParams() = %+v, want {ID:12 Slug:hello Page:3 Draft:false Score:1.5}", p)
+	}
+
+	c.Params = map[string]string{"id": "abc"}
+	err := c.ReadParams(&p)
+	if e, ok := err.(HTTPError); !ok || e.Code() != http.StatusBadRequest {
+		t.Errorf("ReadParams() with an invalid value = %v, want an HTTPError of status 400", err)
+	}
+
+	if err := c.ReadParams(p); err == nil {
+		t.Errorf("ReadParams() with a non-pointer returned no error")
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright 2015 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Fixture is an example request and its response recorded for a route.
+type Fixture struct {
+	Route    string          `json:"route"`    // the name of the route, or its methods and pattern if it has no name
+	Request  FixtureRequest  `json:"request"`  // the recorded request
+	Response FixtureResponse `json:"response"` // the recorded response
+}
+
+// FixtureRequest is a request recorded in a Fixture.
+type FixtureRequest struct {
+	Method      string `json:"method"`
+	URL         string `json:"url"`
+	ContentType string `json:"contentType,omitempty"`
+	Body        string `json:"body,omitempty"`
+}
+
+// FixtureResponse is a response recorded in a Fixture.
+type FixtureResponse struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"contentType,omitempty"`
+	Body        string `json:"body,omitempty"`
+}
+
+var fixtureNameRegex = regexp.MustCompile(`[^\w\-.]+`)
+
+// RecordFixtures returns a handler that records the requests and responses handled by the subsequent handlers
+// as fixtures in the specified directory. The fixtures of a route are stored in a JSON file named after the route
+// (see Fixture.Route). A fixture replaces the previously recorded one with the same request method, URL and body.
+//
+// The recorded fixtures serve as golden files describing the contract of the routes. They can be replayed
+// by calling VerifyFixtures() in tests to detect regressions. RecordFixtures is usually installed as one of the
+// first handlers of a router when exercising an application to capture examples.
+func RecordFixtures(dir string) Handler {
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(RootPath, dir)
+	}
+	var mu sync.Mutex
+
+	return func(c *Context) {
+		route := c.Route
+		var body []byte
+		if c.Request.Body != nil {
+			body, _ = ioutil.ReadAll(c.Request.Body)
+			c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+
+		rw := &responseRecorder{ResponseWriter: c.Response}
+		c.Response = rw
+		c.Next()
+		c.Response = rw.ResponseWriter
+
+		// no other route has handled the request
+		if c.Route == nil || c.Route == route {
+			return
+		}
+		fixture := Fixture{
+			Route: routeKey(c.Route),
+			Request: FixtureRequest{
+				Method:      c.Request.Method,
+				URL:         c.Request.URL.RequestURI(),
+				ContentType: c.Request.Header.Get("Content-Type"),
+				Body:        string(body),
+			},
+			Response: FixtureResponse{
+				Status:      rw.Status(),
+				ContentType: rw.contentType,
+				Body:        rw.body.String(),
+			},
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if err := saveFixture(dir, fixture); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// VerifyFixtures replays the fixtures recorded in the specified directory through the given handler (usually a Router)
+// and compares the responses with the recorded ones. Response bodies of JSON content are compared by their values
+// rather than their textual representation. An error describing all mismatches is returned if any.
+//
+// VerifyFixtures is meant to be called in tests. For example,
+//
+//   func TestContract(t *testing.T) {
+//       if err := routing.VerifyFixtures(newRouter(), "testdata/fixtures"); err != nil {
+//           t.Error(err)
+//       }
+//   }
+func VerifyFixtures(handler http.Handler, dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	var mismatches []string
+	for _, file := range files {
+		fixtures, err := loadFixtures(file)
+		if err != nil {
+			return err
+		}
+		for _, fixture := range fixtures {
+			req, err := http.NewRequest(fixture.Request.Method, fixture.Request.URL, strings.NewReader(fixture.Request.Body))
+			if err != nil {
+				return err
+			}
+			if fixture.Request.ContentType != "" {
+				req.Header.Set("Content-Type", fixture.Request.ContentType)
+			}
+			rw := &responseRecorder{header: make(http.Header)}
+			handler.ServeHTTP(rw, req)
+
+			if msg := compareFixtureResponse(fixture.Response, rw); msg != "" {
+				mismatches = append(mismatches, fmt.Sprintf("%v: %v %v: %v", fixture.Route, fixture.Request.Method, fixture.Request.URL, msg))
+			}
+		}
+	}
+
+	if len(mismatches) > 0 {
+		return FixtureError(mismatches)
+	}
+	return nil
+}
+
+// FixtureError describes the mismatches between the recorded fixtures and the actual responses.
+type FixtureError []string
+
+// Error returns the error message represented by FixtureError.
+func (e FixtureError) Error() string {
+	return "Fixture mismatches:\n" + strings.Join(e, "\n")
+}
+
+// routeKey returns a string identifying the route.
+func routeKey(route *Route) string {
+	if route.Name != "" {
+		return route.Name
+	}
+	var methods []string
+	for method := range route.Methods {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	if len(methods) == 0 {
+		return route.Pattern
+	}
+	return strings.Join(methods, ",") + " " + route.Pattern
+}
+
+// compareFixtureResponse returns a message describing the difference between the expected and actual responses,
+// or an empty string if they match.
+func compareFixtureResponse(expected FixtureResponse, actual *responseRecorder) string {
+	if actual.Status() != expected.Status {
+		return fmt.Sprintf("status = %v, want %v", actual.Status(), expected.Status)
+	}
+	contentType := actual.contentType
+	if contentType != expected.ContentType {
+		return fmt.Sprintf("Content-Type = %q, want %q", contentType, expected.ContentType)
+	}
+	body := actual.body.String()
+	if body == expected.Body {
+		return ""
+	}
+	if strings.Contains(contentType, "json") {
+		var v1, v2 interface{}
+		if json.Unmarshal([]byte(body), &v1) == nil && json.Unmarshal([]byte(expected.Body), &v2) == nil && reflect.DeepEqual(v1, v2) {
+			return ""
+		}
+	}
+	return fmt.Sprintf("body = %q, want %q", body, expected.Body)
+}
+
+func loadFixtures(file string) ([]Fixture, error) {
+	var fixtures []Fixture
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		return nil, fmt.Errorf("%v: %v", file, err)
+	}
+	return fixtures, nil
+}
+
+func saveFixture(dir string, fixture Fixture) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	file := filepath.Join(dir, strings.Trim(fixtureNameRegex.ReplaceAllString(fixture.Route, "_"), "_") + ".json")
+	fixtures, err := loadFixtures(file)
+	if err != nil {
+		return err
+	}
+
+	replaced := false
+	for i, f := range fixtures {
+		if f.Request.Method == fixture.Request.Method && f.Request.URL == fixture.Request.URL && f.Request.Body == fixture.Request.Body {
+			fixtures[i] = fixture
+			replaced = true
+		}
+	}
+	if !replaced {
+		fixtures = append(fixtures, fixture)
+	}
+
+	data, err := json.MarshalIndent(fixtures, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, data, 0644)
+}
@@ -0,0 +1,83 @@
+// Copyright 2015 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"testing"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func newFixtureRouter(version string, handlers ...Handler) *Router {
+	r := NewRouter()
+	if len(handlers) > 0 {
+		r.Use(handlers...)
+	}
+	r.Get("/users/<id>", func(c *Context) string {
+		c.Response.Header().Set("Content-Type", "application/json")
+		if version == "v1" {
+			return `{"id": "` + c.Params["id"] + `", "name": "abc"}`
+		}
+		return `{"name":"abc","id":"` + c.Params["id"] + `"}`
+	}).Name = "user"
+	r.Post("/users", func(c *Context) string {
+		data, _ := ioutil.ReadAll(c.Request.Body)
+		c.Response.WriteHeader(http.StatusCreated)
+		return version + ":" + string(data)
+	})
+	return r
+}
+
+func TestRecordAndVerifyFixtures(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ozzo-routing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	r := newFixtureRouter("v1", RecordFixtures(dir))
+
+	requests := []struct {
+		method string
+		url    string
+		body   string
+	}{
+		{"GET", "/users/1", ""},
+		{"GET", "/users/2?fields=name", ""},
+		{"GET", "/users/1", ""},
+		{"POST", "/users", "abc"},
+		{"GET", "/unknown", ""},
+	}
+	for _, rq := range requests {
+		req, _ := http.NewRequest(rq.method, rq.url, strings.NewReader(rq.body))
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 2 {
+		t.Fatalf("RecordFixtures() created %v files, want 2", len(files))
+	}
+	fixtures, err := loadFixtures(filepath.Join(dir, "user.json"))
+	if err != nil || len(fixtures) != 2 {
+		t.Fatalf("RecordFixtures() recorded %v fixtures for route user, want 2 (error: %v)", len(fixtures), err)
+	}
+	if fixtures[1].Request.URL != "/users/2?fields=name" || fixtures[1].Response.Status != http.StatusOK || fixtures[1].Response.ContentType != "application/json" {
+		t.Errorf("RecordFixtures() recorded unexpected fixture %+v", fixtures[1])
+	}
+
+	if err := VerifyFixtures(newFixtureRouter("v1"), dir); err != nil {
+		t.Errorf("VerifyFixtures() with the same router returned %v", err)
+	}
+
+	// JSON responses are compared by values
+	err = VerifyFixtures(newFixtureRouter("v2"), dir)
+	if e, ok := err.(FixtureError); !ok || len(e) != 1 || !strings.HasPrefix(e[0], "POST /users: POST /users: body = ") {
+		t.Errorf("VerifyFixtures() with a changed router returned %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2015 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// DebugInfo is the diagnostic information of an error, which is collected when the router is in debug mode.
+type DebugInfo struct {
+	Error    string            // the error recovered from panic
+	Route    string            // the name (or the methods and the pattern) of the route that was being handled
+	Handlers []string          // the names of the handlers of the route
+	Params   map[string]string // the URL parameter values when the error occurred
+	Stack    string            // the stack trace of the panic
+}
+
+// DebugInfo returns the diagnostic information of Context.Error. It returns nil if there is no error,
+// or if the router is not in debug mode (see Router.Debug).
+func (c *Context) DebugInfo() *DebugInfo {
+	if c.Error == nil {
+		return nil
+	}
+	return c.debug
+}
+
+// String returns the diagnostic information in a human readable format.
+func (d *DebugInfo) String() string {
+	var params []string
+	for name, value := range d.Params {
+		params = append(params, name + "=" + value)
+	}
+	sort.Strings(params)
+	return fmt.Sprintf("Error: %v\nRoute: %v\nHandlers: %v\nParams: %v\n\n%v", d.Error, d.Route,
+		strings.Join(d.Handlers, ", "), strings.Join(params, ", "), d.Stack)
+}
+
+// DebugErrorHandler returns a handler that handles the error recorded in Context.Error like ErrorHandler,
+// except that when the router is in debug mode (see Router.Debug), the diagnostic information of the error,
+// including the matched route, its handlers, the URL parameters and the stack trace, is attached to the response.
+// When the router is not in debug mode, only the status text is sent for errors other than HTTPError, and
+// the errors are logged using the specified LogFunc (if it is not nil). For example,
+//
+//   r := routing.NewRouter()
+//   r.Debug = os.Getenv("APP_DEBUG") != ""
+//   r.Error(routing.DebugErrorHandler(log.Printf))
+//
+// This handler is usually used as one of the last handlers for a router.
+func DebugErrorHandler(f LogFunc) Handler {
+	return func(c *Context) string {
+		status, message := http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError)
+		if err, ok := c.Error.(HTTPError); ok {
+			status, message = err.Code(), err.Error()
+		} else if f != nil {
+			f("%v", c.Error)
+		}
+
+		c.Response.WriteHeader(status)
+		if info := c.DebugInfo(); info != nil {
+			return message + "\n\n" + info.String()
+		}
+		return message
+	}
+}
+
+// newDebugInfo collects the diagnostic information of the given error recovered from panic.
+func newDebugInfo(c *Context, err interface{}, stack []byte) *DebugInfo {
+	info := &DebugInfo{
+		Error:  fmt.Sprint(err),
+		Params: copyParams(c.Params),
+		Stack:  string(stack),
+	}
+	if c.Route != nil {
+		info.Route = routeKey(c.Route)
+		for _, handler := range c.Route.Handlers {
+			info.Handlers = append(info.Handlers, handlerName(handler))
+		}
+	}
+	return info
+}
+
+// handlerName returns the name of the function of the given handler.
+func handlerName(handler Handler) string {
+	v := reflect.ValueOf(handler)
+	if v.Kind() == reflect.Func {
+		if fn := runtime.FuncForPC(v.Pointer()); fn != nil {
+			return fn.Name()
+		}
+	}
+	return fmt.Sprintf("%T", handler)
+}
@@ -0,0 +1,94 @@
+// Copyright 2015 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"testing"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+func nextHandler(c *Context) {
+	c.Next()
+}
+
+func failingHandler(c *Context) {
+	panic(errors.New("db is down"))
+}
+
+func TestDebugErrorHandler(t *testing.T) {
+	var logged string
+	r := NewRouter()
+	r.Get("/users/<id>", nextHandler, failingHandler).Name = "user"
+	r.Get("/posts", func(c *Context) {
+		c.Panic(http.StatusForbidden, "no access")
+	})
+	r.Error(DebugErrorHandler(func(format string, a ...interface{}) {
+		logged = a[0].(error).Error()
+	}))
+
+	tests := []struct {
+		// input
+		debug    bool
+		path     string
+		// output
+		status   int
+		body     []string
+		logged   string
+	}{
+		{false, "/users/1", http.StatusInternalServerError, []string{"Internal Server Error"}, "db is down"},
+		{false, "/posts", http.StatusForbidden, []string{"no access"}, ""},
+		{true, "/users/1", http.StatusInternalServerError, []string{"Internal Server Error\n\nError: db is down\nRoute: user\n",
+			"Handlers: github.com/go-ozzo/ozzo-routing.nextHandler, github.com/go-ozzo/ozzo-routing.failingHandler\nParams: id=1\n",
+			"ozzo-routing.failingHandler("}, "db is down"},
+		{true, "/posts", http.StatusForbidden, []string{"no access\n\nError: no access\nRoute: GET /posts\n"}, ""},
+	}
+
+	for _, tt := range tests {
+		logged = ""
+		r.Debug = tt.debug
+		req, _ := http.NewRequest("GET", tt.path, nil)
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		if res.Code != tt.status {
+			t.Errorf("Debug(%v, %q).status = %v, want %v", tt.debug, tt.path, res.Code, tt.status)
+		}
+		body := res.Body.String()
+		if !tt.debug && body != tt.body[0] {
+			t.Errorf("Debug(%v, %q) = %q, want %q", tt.debug, tt.path, body, tt.body[0])
+		}
+		for _, s := range tt.body {
+			if !strings.Contains(body, s) {
+				t.Errorf("Debug(%v, %q) = %q, want it to contain %q", tt.debug, tt.path, body, s)
+			}
+		}
+		if logged != tt.logged {
+			t.Errorf("Debug(%v, %q) logged %q, want %q", tt.debug, tt.path, logged, tt.logged)
+		}
+	}
+
+	c := NewContext(nil, nil)
+	if c.DebugInfo() != nil {
+		t.Errorf("DebugInfo() without an error = %v, want nil", c.DebugInfo())
+	}
+}
+
+func TestDebugSnapshot(t *testing.T) {
+	next := NewRouter()
+	next.Debug = true
+	next.Get("/users", failingHandler)
+	next.Error(DebugErrorHandler(nil))
+
+	r := NewRouter()
+	r.Swap(next.Compile())
+	req, _ := http.NewRequest("GET", "/users", nil)
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	if !strings.Contains(res.Body.String(), "Error: db is down") {
+		t.Errorf("Debug(snapshot) = %q, want debug information", res.Body.String())
+	}
+}
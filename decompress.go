@@ -0,0 +1,96 @@
+// Copyright 2015 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// RequestDecompressor returns a handler that transparently decompresses request bodies encoded with gzip or deflate,
+// as indicated by the Content-Encoding request header. Subsequent handlers will read the decompressed body,
+// and the Content-Encoding and Content-Length headers will be removed from the request.
+//
+// To protect the server against decompression bombs, reading more than limit bytes of the decompressed body
+// will fail with an HTTPError of the status http.StatusRequestEntityTooLarge (413). If limit is not positive,
+// the decompressed body is not limited, and routes declaring Route.MaxLength reject the request with an HTTPError
+// of the status http.StatusLengthRequired (411) because the decompressed length is unknown.
+//
+// A request with an unsupported content encoding is rejected with an HTTPError of the status
+// http.StatusUnsupportedMediaType (415), and a request whose body is not validly compressed is rejected
+// with an HTTPError of the status http.StatusBadRequest (400).
+func RequestDecompressor(limit int64) Handler {
+	return func(c *Context) {
+		encoding := strings.ToLower(strings.TrimSpace(c.Request.Header.Get("Content-Encoding")))
+		if encoding == "" || encoding == "identity" || c.Request.Body == nil {
+			c.Next()
+			return
+		}
+
+		var (
+			body io.ReadCloser
+			err  error
+		)
+		switch encoding {
+		case "gzip", "x-gzip":
+			body, err = gzip.NewReader(c.Request.Body)
+		case "deflate":
+			body, err = newDeflateReader(c.Request.Body)
+		default:
+			c.Panic(http.StatusUnsupportedMediaType)
+		}
+		if err != nil {
+			c.Panic(http.StatusBadRequest)
+		}
+
+		c.Request.Body = &decompressedBody{Reader: body, body: body, raw: c.Request.Body}
+		if limit > 0 {
+			c.Request.Body = &limitedBody{ReadCloser: c.Request.Body, limit: limit}
+		}
+		c.Request.Header.Del("Content-Encoding")
+		c.Request.Header.Del("Content-Length")
+		c.Request.ContentLength = -1
+		c.Next()
+	}
+}
+
+// newDeflateReader returns a reader of deflate-encoded content. Although the deflate content encoding is defined
+// as zlib-wrapped data, some clients send raw deflate data, which is also supported.
+func newDeflateReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+	if header[0] & 0x0f == 8 && (uint(header[0]) << 8 | uint(header[1])) % 31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+// decompressedBody reads the decompressed request body.
+type decompressedBody struct {
+	io.Reader
+	body io.Closer
+	raw  io.Closer
+}
+
+func (b *decompressedBody) Read(p []byte) (int, error) {
+	n, err := b.Reader.Read(p)
+	if err != nil && err != io.EOF {
+		err = NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return n, err
+}
+
+func (b *decompressedBody) Close() error {
+	b.body.Close()
+	return b.raw.Close()
+}
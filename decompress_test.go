@@ -0,0 +1,127 @@
+// Copyright 2015 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"testing"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+func compress(encoding, data string) string {
+	var (
+		buf bytes.Buffer
+		w   io.WriteCloser
+	)
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "zlib":
+		w = zlib.NewWriter(&buf)
+	default:
+		w, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	}
+	w.Write([]byte(data))
+	w.Close()
+	return buf.String()
+}
+
+func TestRequestDecompressor(t *testing.T) {
+	r := NewRouter()
+	r.Use(RequestDecompressor(10))
+	r.Post("/data", func(c *Context) string {
+		data, err := ioutil.ReadAll(c.Request.Body)
+		if err != nil {
+			panic(err)
+		}
+		return fmt.Sprintf("%v:%v:%s", c.Request.Header.Get("Content-Encoding"), c.Request.ContentLength, data)
+	})
+	r.Error(func(c *Context) {
+		if err, ok := c.Error.(HTTPError); ok {
+			fmt.Fprintf(c.Response, "<err:%v>", err.Code())
+		}
+	})
+
+	tests := []struct {
+		// input
+		encoding string
+		body     string
+		// output
+		result   string
+	}{
+		{"", "abc", ":3:abc"},
+		{"identity", "abc", "identity:3:abc"},
+		{"gzip", compress("gzip", "abcdef"), ":-1:abcdef"},
+		{"X-GZIP", compress("gzip", "abcdefghij"), ":-1:abcdefghij"},
+		{"deflate", compress("zlib", "abcdef"), ":-1:abcdef"},
+		{"deflate", compress("flate", "abcdef"), ":-1:abcdef"},
+		{"gzip", compress("gzip", "abcdefghijk"), "<err:413>"},
+		{"gzip", "abcdef", "<err:400>"},
+		{"gzip", compress("gzip", "abcdef")[:15], "<err:400>"},
+		{"br", "abcdef", "<err:415>"},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest("POST", "/data", strings.NewReader(tt.body))
+		if tt.encoding != "" {
+			req.Header.Set("Content-Encoding", tt.encoding)
+		}
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		if res.Body.String() != tt.result {
+			t.Errorf("Dispatch(%q) = %q, want %q", tt.encoding, res.Body.String(), tt.result)
+		}
+	}
+}
+
+func TestRequestDecompressorMaxLength(t *testing.T) {
+	r := NewRouter()
+	r.Group("/limited", func(r *Router) {
+		r.Post("/data", func(c *Context) string {
+			data, err := ioutil.ReadAll(c.Request.Body)
+			if err != nil {
+				panic(err)
+			}
+			return string(data)
+		}).MaxLength(5)
+	}, RequestDecompressor(100))
+	r.Group("/unlimited", func(r *Router) {
+		r.Post("/data", handle("data")).MaxLength(5)
+	}, RequestDecompressor(0))
+	r.Error(func(c *Context) {
+		if err, ok := c.Error.(HTTPError); ok {
+			fmt.Fprintf(c.Response, "<err:%v>", err.Code())
+		}
+	})
+
+	tests := []struct {
+		path   string
+		body   string
+		result string
+	}{
+		{"/limited/data", compress("gzip", "abc"), "abc"},
+		{"/limited/data", compress("gzip", "abcde"), "abcde"},
+		{"/limited/data", compress("gzip", "abcdef"), "<err:413>"},
+		{"/unlimited/data", compress("gzip", "abc"), "<err:411>"},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest("POST", tt.path, strings.NewReader(tt.body))
+		req.Header.Set("Content-Encoding", "gzip")
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		if res.Body.String() != tt.result {
+			t.Errorf("Dispatch(%q, %q) = %q, want %q", tt.path, tt.body, res.Body.String(), tt.result)
+		}
+	}
+}
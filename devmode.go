@@ -0,0 +1,235 @@
+// Copyright 2015 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DevOptions defines the possible options for the DevReloader handler.
+type DevOptions struct {
+	// The directories (e.g. templates, static files) to be watched for file changes.
+	// Relative paths are resolved against RootPath.
+	Dirs         []string
+	// The interval between two scans of the watched directories. It is defaulted to one second.
+	Interval     time.Duration
+	// A function to be called with the path of every file that is added, modified or removed.
+	// This is usually used to invalidate the caches of template renderers.
+	OnChange     func(path string)
+	// Whether to inject a script into HTML responses, which reloads the page in the browser
+	// when any watched file is changed. Only HTML responses are buffered for this purpose.
+	InjectScript bool
+	// The URL path polled by the injected script. It is defaulted to "/__reload".
+	Path         string
+	// The channel whose closing stops watching the directories. If nil, the directories are watched
+	// until the process exits.
+	Done         <-chan struct{}
+}
+
+// DevReloader returns a handler that supports the development mode by watching files for changes.
+//
+// The handler scans the directories specified in the options periodically in a background goroutine
+// (until DevOptions.Done is closed) and calls DevOptions.OnChange for each changed file. If DevOptions.InjectScript
+// is true, the handler also injects a script into HTML responses which polls DevOptions.Path and reloads the page
+// once a change is detected. Because the Static and StaticFile handlers read files for every request,
+// the reloaded page always reflects the latest static files.
+//
+// DevReloader should only be used during development, usually as one of the first handlers of a router:
+//
+//   r.Use(routing.DevReloader(routing.DevOptions{
+//       Dirs:         []string{"templates", "web"},
+//       OnChange:     func(string) { renderer.ClearCache() },
+//       InjectScript: true,
+//   }))
+func DevReloader(opts DevOptions) Handler {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	if opts.Path == "" {
+		opts.Path = "/__reload"
+	}
+	dirs := make([]string, len(opts.Dirs))
+	for i, dir := range opts.Dirs {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(RootPath, dir)
+		}
+		dirs[i] = dir
+	}
+
+	var (
+		mu      sync.Mutex
+		version int
+	)
+	go func() {
+		files := scanFiles(dirs)
+		for {
+			select {
+			case <-time.After(opts.Interval):
+			case <-opts.Done:
+				return
+			}
+			current := scanFiles(dirs)
+			changes := diffFiles(files, current)
+			files = current
+			if len(changes) == 0 {
+				continue
+			}
+			if opts.OnChange != nil {
+				for _, path := range changes {
+					opts.OnChange(path)
+				}
+			}
+			mu.Lock()
+			version++
+			mu.Unlock()
+		}
+	}()
+
+	script := fmt.Sprintf(liveReloadScript, opts.Path, int64(opts.Interval / time.Millisecond))
+
+	return func(c *Context) {
+		if opts.InjectScript && c.Request.URL.Path == opts.Path {
+			mu.Lock()
+			v := version
+			mu.Unlock()
+			c.Response.Header().Set("Cache-Control", "no-cache")
+			c.Response.Write([]byte(strconv.Itoa(v)))
+			return
+		}
+		if !opts.InjectScript {
+			c.Next()
+			return
+		}
+
+		rw := &scriptResponseWriter{ResponseWriter: c.Response}
+		c.Response = rw
+		c.Next()
+		c.Response = rw.ResponseWriter
+		rw.flush(script)
+	}
+}
+
+// liveReloadScript polls the URL path for the version of the watched files and reloads the page when it changes.
+const liveReloadScript = `<script>(function(){var v=null;function poll(){var x=new XMLHttpRequest();` +
+	`x.onload=function(){if(v!==null&&x.responseText!==v){location.reload();return}v=x.responseText;setTimeout(poll,%[2]d)};` +
+	`x.onerror=function(){setTimeout(poll,%[2]d)};x.open("GET","%[1]s");x.send()}poll()})();</script>`
+
+// scanFiles returns the modification time and size of every file under the given directories.
+func scanFiles(dirs []string) map[string]string {
+	files := make(map[string]string)
+	for _, dir := range dirs {
+		filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				files[path] = fmt.Sprintf("%v:%v", info.ModTime().UnixNano(), info.Size())
+			}
+			return nil
+		})
+	}
+	return files
+}
+
+// diffFiles returns the paths of the files that are added, modified or removed between two scans.
+func diffFiles(old, current map[string]string) []string {
+	var changes []string
+	for path, stamp := range current {
+		if old[path] != stamp {
+			changes = append(changes, path)
+		}
+	}
+	for path := range old {
+		if _, ok := current[path]; !ok {
+			changes = append(changes, path)
+		}
+	}
+	return changes
+}
+
+// scriptResponseWriter injects a script into HTML responses. Whether the response is HTML is decided when it is
+// first written: HTML responses are buffered so that the script can be injected, while other responses are written
+// through unchanged, so that streaming and flushing keep working.
+type scriptResponseWriter struct {
+	http.ResponseWriter
+	status    int
+	decided   bool
+	buffering bool
+	buf       bytes.Buffer
+}
+
+func (w *scriptResponseWriter) Write(p []byte) (int, error) {
+	if !w.decided {
+		w.decide(p)
+	}
+	if w.buffering {
+		return w.buf.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *scriptResponseWriter) WriteHeader(status int) {
+	if w.status != 0 {
+		return
+	}
+	w.status = status
+	if w.ResponseWriter.Header().Get("Content-Type") != "" {
+		w.decide(nil)
+	}
+}
+
+// Flush sends the written data to the client, unless the response is buffered for injecting the script.
+func (w *scriptResponseWriter) Flush() {
+	if !w.decided {
+		w.decide(nil)
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok && !w.buffering {
+		f.Flush()
+	}
+}
+
+// decide determines whether the response should be buffered. If the response does not declare its Content-Type,
+// the type is detected from the first content written. Responses that are not buffered are written through
+// from this point on.
+func (w *scriptResponseWriter) decide(p []byte) {
+	w.decided = true
+	header := w.ResponseWriter.Header()
+	if header.Get("Content-Type") == "" && len(p) > 0 {
+		header.Set("Content-Type", http.DetectContentType(p))
+	}
+	w.buffering = strings.HasPrefix(header.Get("Content-Type"), "text/html") && header.Get("Content-Encoding") == ""
+	if !w.buffering && w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+// flush writes the buffered HTML response to the underlying writer, injecting the script into the content.
+func (w *scriptResponseWriter) flush(script string) {
+	if !w.decided {
+		w.decide(nil)
+	}
+	if !w.buffering {
+		return
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	body := w.buf.Bytes()
+	if len(body) > 0 {
+		if i := bytes.LastIndex(bytes.ToLower(body), []byte("</body>")); i >= 0 {
+			body = append(body[:i:i], append([]byte(script), body[i:]...)...)
+		} else {
+			body = append(body, script...)
+		}
+		w.ResponseWriter.Header().Del("Content-Length")
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(body)
+}
@@ -0,0 +1,128 @@
+// Copyright 2015 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"testing"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+func TestDiffFiles(t *testing.T) {
+	old := map[string]string{"a": "1", "b": "1", "c": "1"}
+	current := map[string]string{"a": "1", "b": "2", "d": "1"}
+	changes := diffFiles(old, current)
+	sort.Strings(changes)
+	if strings.Join(changes, ",") != "b,c,d" {
+		t.Errorf("diffFiles() = %v, want %v", changes, "[b c d]")
+	}
+}
+
+func TestDevReloaderInjectScript(t *testing.T) {
+	r := NewRouter()
+	done := make(chan struct{})
+	defer close(done)
+	r.Use(DevReloader(DevOptions{InjectScript: true, Path: "/reload", Done: done}))
+	r.Get("/page", func() string {
+		return "<html><body>page</body></html>"
+	})
+	r.Get("/text", func(c *Context) string {
+		c.Response.Header().Set("Content-Type", "text/plain")
+		return "text</body>"
+	})
+	r.Get("/stream", func(c *Context) {
+		c.Response.Header().Set("Content-Type", "text/event-stream")
+		c.Response.Write([]byte("data: 1\n\n"))
+		c.Response.(http.Flusher).Flush()
+		if !c.Response.(*scriptResponseWriter).ResponseWriter.(*httptest.ResponseRecorder).Flushed {
+			c.Response.Write([]byte("not flushed"))
+		}
+	})
+
+	script := strings.Split(liveReloadScript, "%")[0]
+	tests := []struct {
+		path   string
+		prefix string
+		suffix string
+	}{
+		{"/page", "<html><body>page" + script, "</script></body></html>"},
+		{"/text", "text</body>", "text</body>"},
+		{"/stream", "data: 1\n\n", "data: 1\n\n"},
+		{"/reload", "0", "0"},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", tt.path, nil)
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		body := res.Body.String()
+		if !strings.HasPrefix(body, tt.prefix) || !strings.HasSuffix(body, tt.suffix) {
+			t.Errorf("Dispatch(%q) = %q, want %q...%q", tt.path, body, tt.prefix, tt.suffix)
+		}
+	}
+}
+
+func TestDevReloaderWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ozzo-routing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		mu      sync.Mutex
+		changed []string
+	)
+	done := make(chan struct{})
+	r := NewRouter()
+	r.Use(DevReloader(DevOptions{
+		Dirs:         []string{dir},
+		Done:         done,
+		Interval:     10 * time.Millisecond,
+		InjectScript: true,
+		OnChange: func(path string) {
+			mu.Lock()
+			changed = append(changed, path)
+			mu.Unlock()
+		},
+	}))
+
+	time.Sleep(30 * time.Millisecond)
+	file := filepath.Join(dir, "index.html")
+	if err := ioutil.WriteFile(file, []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 100; i++ {
+		time.Sleep(10 * time.Millisecond)
+		req, _ := http.NewRequest("GET", "/__reload", nil)
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		if res.Body.String() != "0" {
+			break
+		}
+	}
+
+	// no more changes are reported after the watching is stopped
+	close(done)
+	time.Sleep(30 * time.Millisecond)
+	if err := ioutil.WriteFile(filepath.Join(dir, "other.html"), []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(30 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(changed) != 1 || changed[0] != file {
+		t.Errorf("OnChange() called with %v, want %v", changed, []string{file})
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2015 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// GuardOptions defines the possible options for the RequestGuard handler and ConfigureServer.
+type GuardOptions struct {
+	// The maximum length of the request URI. Longer URIs are rejected with status 414. Zero means no limit.
+	MaxURLLength      int
+	// The maximum total size of the request headers in bytes. Larger headers are rejected with status 431.
+	// Zero means no limit. It is also used as http.Server.MaxHeaderBytes by ConfigureServer.
+	MaxHeaderBytes    int
+	// The maximum number of request header fields. Requests with more fields are rejected with status 431.
+	// Zero means no limit.
+	MaxHeaderCount    int
+	// The maximum time for reading the request headers, used by ConfigureServer to fend off slow clients.
+	ReadHeaderTimeout time.Duration
+	// The maximum time for reading the entire request, used by ConfigureServer to fend off slow clients.
+	ReadTimeout       time.Duration
+	// The URL paths that are never used by the application but are commonly probed by malicious bots,
+	// such as "/wp-login.php". Requests to these paths are rejected with status 404 and counted.
+	Honeypots         []string
+	// The counters of the rejected requests. If not nil, they will be updated by RequestGuard.
+	Counters          *GuardCounters
+}
+
+// GuardCounters counts the requests rejected by RequestGuard. The counters can be read concurrently
+// while requests are being served, which allows them to be reported as metrics.
+type GuardCounters struct {
+	urlTooLong      int64
+	headersTooLarge int64
+	malformed       int64
+	honeypot        int64
+}
+
+// URLTooLong returns the number of requests rejected because of too long URLs.
+func (c *GuardCounters) URLTooLong() int64 {
+	return atomic.LoadInt64(&c.urlTooLong)
+}
+
+// HeadersTooLarge returns the number of requests rejected because of too large or too many headers.
+func (c *GuardCounters) HeadersTooLarge() int64 {
+	return atomic.LoadInt64(&c.headersTooLarge)
+}
+
+// Malformed returns the number of requests rejected because they are malformed.
+func (c *GuardCounters) Malformed() int64 {
+	return atomic.LoadInt64(&c.malformed)
+}
+
+// Honeypot returns the number of requests rejected because they access honeypot paths.
+func (c *GuardCounters) Honeypot() int64 {
+	return atomic.LoadInt64(&c.honeypot)
+}
+
+// RequestGuard returns a handler that rejects suspicious requests before they reach other handlers.
+// Requests whose URIs or headers exceed the limits specified in the options are rejected with
+// an HTTPError of the status 414 or 431. Malformed requests, such as those with invalid URL paths,
+// invalid query strings or missing Host headers, are rejected with an HTTPError of the status 400.
+// Requests to honeypot paths are rejected with an HTTPError of the status 404.
+//
+// RequestGuard is usually registered as the first handler of a router. To protect the server against
+// slow clients, also call ConfigureServer with the same options.
+func RequestGuard(opts GuardOptions) Handler {
+	honeypots := make(map[string]bool)
+	for _, path := range opts.Honeypots {
+		honeypots[path] = true
+	}
+
+	return func(c *Context) {
+		req := c.Request
+		uri := req.RequestURI
+		if uri == "" {
+			uri = req.URL.RequestURI()
+		}
+		if opts.MaxURLLength > 0 && len(uri) > opts.MaxURLLength {
+			if opts.Counters != nil {
+				atomic.AddInt64(&opts.Counters.urlTooLong, 1)
+			}
+			c.Panic(http.StatusRequestURITooLong)
+		}
+
+		if opts.MaxHeaderBytes > 0 || opts.MaxHeaderCount > 0 {
+			size, count := 0, 0
+			for name, values := range req.Header {
+				for _, value := range values {
+					// name, colon, space, value, CRLF
+					size += len(name) + len(value) + 4
+					count++
+				}
+			}
+			if (opts.MaxHeaderBytes > 0 && size > opts.MaxHeaderBytes) || (opts.MaxHeaderCount > 0 && count > opts.MaxHeaderCount) {
+				if opts.Counters != nil {
+					atomic.AddInt64(&opts.Counters.headersTooLarge, 1)
+				}
+				c.Panic(http.StatusRequestHeaderFieldsTooLarge)
+			}
+		}
+
+		if isMalformedRequest(req) {
+			if opts.Counters != nil {
+				atomic.AddInt64(&opts.Counters.malformed, 1)
+			}
+			c.Panic(http.StatusBadRequest)
+		}
+
+		if honeypots[req.URL.Path] {
+			if opts.Counters != nil {
+				atomic.AddInt64(&opts.Counters.honeypot, 1)
+			}
+			c.Panic(http.StatusNotFound)
+		}
+
+		c.Next()
+	}
+}
+
+// ConfigureServer sets the timeouts and header size limit of the given server according to the options,
+// which protects the server against slowloris-style attacks where clients send requests very slowly
+// to exhaust the server connections. Options that are zero leave the server settings unchanged.
+func ConfigureServer(s *http.Server, opts GuardOptions) {
+	if opts.ReadHeaderTimeout > 0 {
+		s.ReadHeaderTimeout = opts.ReadHeaderTimeout
+	}
+	if opts.ReadTimeout > 0 {
+		s.ReadTimeout = opts.ReadTimeout
+	}
+	if opts.MaxHeaderBytes > 0 {
+		s.MaxHeaderBytes = opts.MaxHeaderBytes
+	}
+}
+
+// isMalformedRequest checks if the request URL or Host header is malformed.
+func isMalformedRequest(req *http.Request) bool {
+	path := req.URL.Path
+	if req.Method == "OPTIONS" && req.RequestURI == "*" {
+		path = "/"
+	}
+	if !strings.HasPrefix(path, "/") || strings.IndexFunc(path, isControlChar) >= 0 {
+		return true
+	}
+	// semicolons are tolerated as separators as they are commonly used by older clients
+	if _, err := url.ParseQuery(strings.Replace(req.URL.RawQuery, ";", "&", -1)); err != nil {
+		return true
+	}
+	return req.ProtoAtLeast(1, 1) && req.Host == ""
+}
+
+func isControlChar(r rune) bool {
+	return r < 0x20 || r == 0x7f
+}
@@ -0,0 +1,84 @@
+// Copyright 2015 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"testing"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+)
+
+func TestRequestGuard(t *testing.T) {
+	counters := &GuardCounters{}
+	r := NewRouter()
+	r.Use(RequestGuard(GuardOptions{
+		MaxURLLength:   20,
+		MaxHeaderBytes: 100,
+		MaxHeaderCount: 3,
+		Honeypots:      []string{"/wp-login.php"},
+		Counters:       counters,
+	}))
+	r.Use(handle("ok"))
+	r.Error(func(c *Context) {
+		if err, ok := c.Error.(HTTPError); ok {
+			c.Response.WriteHeader(err.Code())
+		}
+	})
+
+	tests := []struct {
+		// input
+		url     string
+		headers map[string]string
+		host    string
+		// output
+		status  int
+	}{
+		{"/users?a=1", nil, "example.com", http.StatusOK},
+		{"/users?a=1;b=2", nil, "example.com", http.StatusOK},
+		{"/users/1234567890123456", nil, "example.com", http.StatusRequestURITooLong},
+		{"/users", map[string]string{"X-Data": strings.Repeat("a", 100)}, "example.com", http.StatusRequestHeaderFieldsTooLarge},
+		{"/users", map[string]string{"X-A": "1", "X-B": "2", "X-C": "3", "X-D": "4"}, "example.com", http.StatusRequestHeaderFieldsTooLarge},
+		{"/users?a=%zz", nil, "example.com", http.StatusBadRequest},
+		{"/users", nil, "", http.StatusBadRequest},
+		{"/users%01", nil, "example.com", http.StatusBadRequest},
+		{"/wp-login.php", nil, "example.com", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", "http://example.com" + tt.url, nil)
+		req.Host = tt.host
+		for name, value := range tt.headers {
+			req.Header.Set(name, value)
+		}
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		if res.Code != tt.status {
+			t.Errorf("Dispatch(%q).status = %v, want %v", tt.url, res.Code, tt.status)
+		}
+	}
+
+	// a request with a relative path
+	req := &http.Request{Method: "GET", URL: &url.URL{Path: "users"}, Host: "example.com", Proto: "HTTP/1.1", ProtoMajor: 1, ProtoMinor: 1, Header: http.Header{}}
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	if res.Code != http.StatusBadRequest {
+		t.Errorf("Dispatch(%q).status = %v, want %v", "users", res.Code, http.StatusBadRequest)
+	}
+
+	if counters.URLTooLong() != 1 || counters.HeadersTooLarge() != 2 || counters.Malformed() != 4 || counters.Honeypot() != 1 {
+		t.Errorf("GuardCounters = (%v, %v, %v, %v), want (1, 2, 4, 1)", counters.URLTooLong(), counters.HeadersTooLarge(), counters.Malformed(), counters.Honeypot())
+	}
+}
+
+func TestConfigureServer(t *testing.T) {
+	s := &http.Server{ReadTimeout: time.Minute}
+	ConfigureServer(s, GuardOptions{ReadHeaderTimeout: 5 * time.Second, MaxHeaderBytes: 4096})
+	if s.ReadHeaderTimeout != 5 * time.Second || s.ReadTimeout != time.Minute || s.MaxHeaderBytes != 4096 {
+		t.Errorf("ConfigureServer() = (%v, %v, %v), want (%v, %v, %v)", s.ReadHeaderTimeout, s.ReadTimeout, s.MaxHeaderBytes, 5 * time.Second, time.Minute, 4096)
+	}
+}
@@ -70,3 +70,37 @@ func TestTrailingSlashRemover(t *testing.T) {
 
 func TestAccessLogger(t *testing.T) {
 }
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		// input
+		remoteAddr string
+		realIP     string
+		forwarded  string
+		trustProxy bool
+		// output
+		ip         string
+	}{
+		{"10.0.0.1:1234", "", "", false, "10.0.0.1"},
+		{"[::1]:1234", "", "", false, "::1"},
+		{"10.0.0.1", "", "", false, "10.0.0.1"},
+		{"10.0.0.1:1234", "1.2.3.4", "5.6.7.8", false, "10.0.0.1"},
+		{"10.0.0.1:1234", "1.2.3.4", "5.6.7.8", true, "5.6.7.8"},
+		{"10.0.0.1:1234", "1.2.3.4", "", true, "1.2.3.4"},
+		{"10.0.0.1:1234", "", "10.0.0.2, 2001:db8::1", true, "2001:db8::1"},
+		{"10.0.0.1:1234", "", "10.0.0.2, 8.8.8.8", true, "8.8.8.8"},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.RemoteAddr = tt.remoteAddr
+		if tt.realIP != "" {
+			req.Header.Set("X-Real-IP", tt.realIP)
+		}
+		if tt.forwarded != "" {
+			req.Header.Set("X-Forwarded-For", tt.forwarded)
+		}
+		if ip := clientIP(req, tt.trustProxy); ip != tt.ip {
+			t.Errorf("clientIP(%q, %q, %q, %v) = %q, want %q", tt.remoteAddr, tt.realIP, tt.forwarded, tt.trustProxy, ip, tt.ip)
+		}
+	}
+}
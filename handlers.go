@@ -5,6 +5,7 @@
 package routing
 
 import (
+	"net"
 	"net/http"
 	"strings"
 	"fmt"
@@ -79,7 +80,7 @@ func TrailingSlashRemover(status int) Handler {
 
 // AccessLogger returns a handler that logs a message for every request.
 // The access log messages contain information including client IPs, time used to serve each request, request line,
-// response status and size.
+// response status and size. The client IPs are taken from the X-Forwarded-For and X-Real-IP headers when available.
 func AccessLogger(log LogFunc) Handler {
 	var mu sync.Mutex
 	return func(c *Context) {
@@ -91,27 +92,34 @@ func AccessLogger(log LogFunc) Handler {
 
 		c.Next()
 
-		clientIP := getClientIP(req)
+		ip := clientIP(req, true)
 		elapsed := float64(time.Now().Sub(startTime).Nanoseconds()) / 1e6
 		requestLine := fmt.Sprintf("%s %s %s", req.Method, req.RequestURI, req.Proto)
 		mu.Lock()
 		defer mu.Unlock()
-		log(`[%s] [%.3fms] %s %d %d`, clientIP, elapsed, requestLine, rw.status, rw.bytesWritten)
+		log(`[%s] [%.3fms] %s %d %d`, ip, elapsed, requestLine, rw.status, rw.bytesWritten)
 	}
 }
 
-func getClientIP(req *http.Request) string {
-	ip := req.Header.Get("X-Real-IP")
-	if ip == "" {
-		ip = req.Header.Get("X-Forwarded-For")
-		if ip == "" {
-			ip = req.RemoteAddr
+// clientIP returns the IP address of the client. If trustProxy is true, the address appended to the
+// X-Forwarded-For header by the proxy is used, or the X-Real-IP header if there is no X-Forwarded-For header.
+// Only the rightmost X-Forwarded-For entry is used, because the entries before it may be forged by the client.
+func clientIP(req *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if values := req.Header["X-Forwarded-For"]; len(values) > 0 {
+			ips := strings.Split(values[len(values)-1], ",")
+			if ip := strings.TrimSpace(ips[len(ips)-1]); ip != "" {
+				return ip
+			}
+		}
+		if ip := strings.TrimSpace(req.Header.Get("X-Real-IP")); ip != "" {
+			return ip
 		}
 	}
-	if colon := strings.LastIndex(ip, ":"); colon != -1 {
-		ip = ip[:colon]
+	if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		return host
 	}
-	return ip
+	return req.RemoteAddr
 }
 
 type logResponseWriter struct {
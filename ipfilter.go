@@ -0,0 +1,104 @@
+// Copyright 2015 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// IPListProvider provides lists of allowed and denied IP networks that may change over time,
+// such as lists loaded from a database or a configuration service.
+type IPListProvider interface {
+	// IPLists returns the currently allowed and denied IP networks. It is called for every request.
+	IPLists() (allow, deny []*net.IPNet)
+}
+
+// IPFilterOptions defines the possible options for the IPFilter handler.
+type IPFilterOptions struct {
+	// The IP addresses or CIDR ranges (e.g. "10.0.0.0/8") that are allowed. If the list is empty and
+	// Provider does not provide any allowed networks, all addresses that are not denied are allowed.
+	Allow      []string
+	// The IP addresses or CIDR ranges that are denied. Denying takes precedence over allowing.
+	Deny       []string
+	// The provider of additional allowed and denied networks.
+	Provider   IPListProvider
+	// Whether to determine the client IP using the X-Forwarded-For and X-Real-IP headers. Only the rightmost
+	// X-Forwarded-For entry, which is appended by the proxy, is used. This should only be enabled when the application
+	// is behind a trusted proxy which sets these headers, because otherwise the headers can be forged by clients.
+	TrustProxy bool
+}
+
+// IPFilter returns a handler that rejects requests from disallowed client IP addresses with
+// an HTTPError of the status http.StatusForbidden (403).
+//
+// The handler should be registered before the handlers it protects, either with the root router
+// to filter all requests or with a route group. For example,
+//
+//   r.Group("/admin", func(r *routing.Router) {
+//       // ...
+//   }, routing.IPFilter(routing.IPFilterOptions{
+//       Allow: []string{"10.0.0.0/8", "127.0.0.1"},
+//   }))
+//
+// IPFilter panics if an address in IPFilterOptions.Allow or IPFilterOptions.Deny is invalid.
+func IPFilter(opts IPFilterOptions) Handler {
+	allow := parseIPNets(opts.Allow)
+	deny := parseIPNets(opts.Deny)
+
+	return func(c *Context) {
+		allowed, denied := allow, deny
+		if opts.Provider != nil {
+			a, d := opts.Provider.IPLists()
+			allowed = append(append([]*net.IPNet(nil), allow...), a...)
+			denied = append(append([]*net.IPNet(nil), deny...), d...)
+		}
+
+		ip := net.ParseIP(clientIP(c.Request, opts.TrustProxy))
+		if ip == nil || containsIP(denied, ip) || (len(allowed) > 0 && !containsIP(allowed, ip)) {
+			c.Panic(http.StatusForbidden)
+		}
+		c.Next()
+	}
+}
+
+// ParseIPNet parses an IP address or a CIDR range into an IP network.
+// An IP address is treated as a network containing only that address.
+func ParseIPNet(s string) (*net.IPNet, error) {
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, &net.ParseError{Type: "IP address", Text: s}
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+	}
+	_, ipNet, err := net.ParseCIDR(s)
+	return ipNet, err
+}
+
+func parseIPNets(list []string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, s := range list {
+		ipNet, err := ParseIPNet(strings.TrimSpace(s))
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets
+}
+
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, ipNet := range nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+// Copyright 2015 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"testing"
+	"net"
+	"net/http"
+	"net/http/httptest"
+)
+
+type ipListProviderMock struct {
+	allow, deny []*net.IPNet
+}
+
+func (p *ipListProviderMock) IPLists() (allow, deny []*net.IPNet) {
+	return p.allow, p.deny
+}
+
+func TestParseIPNet(t *testing.T) {
+	tests := []struct {
+		input  string
+		output string
+		valid  bool
+	}{
+		{"10.0.0.0/8", "10.0.0.0/8", true},
+		{"10.1.2.3/8", "10.0.0.0/8", true},
+		{"127.0.0.1", "127.0.0.1/32", true},
+		{"::1", "::1/128", true},
+		{"2001:db8::/32", "2001:db8::/32", true},
+		{"abc", "", false},
+		{"10.0.0.0/33", "", false},
+	}
+
+	for _, tt := range tests {
+		ipNet, err := ParseIPNet(tt.input)
+		if (err == nil) != tt.valid {
+			t.Errorf("ParseIPNet(%q).error = %v, want valid = %v", tt.input, err, tt.valid)
+		} else if err == nil && ipNet.String() != tt.output {
+			t.Errorf("ParseIPNet(%q) = %v, want %v", tt.input, ipNet, tt.output)
+		}
+	}
+}
+
+func TestIPFilter(t *testing.T) {
+	provider := &ipListProviderMock{}
+	r := NewRouter()
+	r.Group("/admin", func(r *Router) {
+		r.Get("/users", handle("admin"))
+	}, IPFilter(IPFilterOptions{
+		Allow:    []string{"10.0.0.0/8", "::1"},
+		Deny:     []string{"10.0.0.13"},
+		Provider: provider,
+	}))
+	r.Group("/proxied", func(r *Router) {
+		r.Get("/users", handle("proxied"))
+	}, IPFilter(IPFilterOptions{
+		Deny:       []string{"192.168.1.0/24"},
+		TrustProxy: true,
+	}))
+	r.Get("/users", handle("users"))
+	r.Error(func(c *Context) {
+		if err, ok := c.Error.(HTTPError); ok {
+			c.Response.Write([]byte(err.Error()))
+		}
+	})
+
+	tests := []struct {
+		// input
+		path       string
+		remoteAddr string
+		forwarded  string
+		// output
+		result     string
+	}{
+		{"/users", "192.168.1.1:1234", "", "<users>"},
+		{"/admin/users", "10.1.2.3:1234", "", "<admin>"},
+		{"/admin/users", "[::1]:1234", "", "<admin>"},
+		{"/admin/users", "10.0.0.13:1234", "", "Forbidden"},
+		{"/admin/users", "192.168.1.1:1234", "10.1.2.3", "Forbidden"},
+		{"/admin/users", "172.16.0.5:1234", "", "Forbidden"},
+		{"/proxied/users", "192.168.1.1:1234", "", "Forbidden"},
+		{"/proxied/users", "192.168.1.1:1234", "192.168.1.1, 8.8.8.8", "<proxied>"},
+		{"/proxied/users", "192.168.1.1:1234", "8.8.8.8, 192.168.1.1", "Forbidden"},
+		{"/proxied/users", "8.8.8.8:1234", "192.168.1.7", "Forbidden"},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", tt.path, nil)
+		req.RemoteAddr = tt.remoteAddr
+		if tt.forwarded != "" {
+			req.Header.Set("X-Forwarded-For", tt.forwarded)
+		}
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		if res.Body.String() != tt.result {
+			t.Errorf("Dispatch(%q) from %q (%q) = %q, want %q", tt.path, tt.remoteAddr, tt.forwarded, res.Body.String(), tt.result)
+		}
+	}
+
+	// dynamic lists
+	_, allowed, _ := net.ParseCIDR("172.16.0.0/12")
+	_, denied, _ := net.ParseCIDR("10.1.0.0/16")
+	provider.allow = []*net.IPNet{allowed}
+	provider.deny = []*net.IPNet{denied}
+	for addr, result := range map[string]string{"172.16.0.5:1": "<admin>", "10.1.2.3:1": "Forbidden", "10.2.0.1:1": "<admin>"} {
+		req, _ := http.NewRequest("GET", "/admin/users", nil)
+		req.RemoteAddr = addr
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		if res.Body.String() != result {
+			t.Errorf("Dispatch(%q) from %q = %q, want %q", "/admin/users", addr, res.Body.String(), result)
+		}
+	}
+}
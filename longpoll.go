@@ -0,0 +1,97 @@
+// Copyright 2015 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// longPolls tracks the requests waiting in Context.Wait() so that they can be released on shutdown.
+type longPolls struct {
+	mu       sync.Mutex
+	count    int
+	released chan struct{}
+}
+
+// Wait blocks the current request until a value is received from the channel ch or the timeout elapses.
+// It is meant to be used by long-poll endpoints. For example,
+//
+//   r.Get("/events", func(c *routing.Context) {
+//       if event, ok := c.Wait(events, 30 * time.Second); ok {
+//           fmt.Fprint(c.Response, event)
+//       }
+//   })
+//
+// If a value is received, Wait returns the value and true. Otherwise, Wait returns false after writing
+// a response: the status http.StatusNoContent (204) if the timeout elapses or the channel is closed,
+// or the status http.StatusServiceUnavailable (503) if the wait is released by Router.ReleaseLongPolls().
+// Wait also returns false if the client closes the connection.
+//
+// The channel may be of any element type. Wait panics if ch is not a channel that can be received from.
+func (c *Context) Wait(ch interface{}, timeout time.Duration) (interface{}, bool) {
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch)},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(time.After(timeout))},
+	}
+	if c.Request != nil {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(c.Request.Context().Done())})
+	}
+	if c.router != nil {
+		released := c.router.trackLongPoll(1)
+		defer c.router.trackLongPoll(-1)
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(released)})
+	}
+
+	chosen, value, ok := reflect.Select(cases)
+	switch {
+	case chosen == 0 && ok:
+		return value.Interface(), true
+	case chosen == 0 || chosen == 1:
+		c.Response.WriteHeader(http.StatusNoContent)
+	case chosen == len(cases) - 1 && c.router != nil:
+		c.Response.WriteHeader(http.StatusServiceUnavailable)
+	}
+	return nil, false
+}
+
+// LongPolls returns the number of requests that are currently waiting in Context.Wait().
+func (r *Router) LongPolls() int {
+	r.longPolls.mu.Lock()
+	defer r.longPolls.mu.Unlock()
+	return r.longPolls.count
+}
+
+// ReleaseLongPolls releases all requests waiting in Context.Wait() and makes subsequent calls of Context.Wait()
+// return immediately. It should be called when the server is shutting down gracefully, because otherwise
+// the shutdown would wait for the long polls to time out. For example,
+//
+//   server := &http.Server{Addr: ":8080", Handler: router}
+//   server.RegisterOnShutdown(router.ReleaseLongPolls)
+func (r *Router) ReleaseLongPolls() {
+	r.longPolls.mu.Lock()
+	defer r.longPolls.mu.Unlock()
+	if r.longPolls.released == nil {
+		r.longPolls.released = make(chan struct{})
+	}
+	select {
+	case <-r.longPolls.released:
+	default:
+		close(r.longPolls.released)
+	}
+}
+
+// trackLongPoll updates the number of waiting requests by delta and returns the channel that is closed upon release.
+func (r *Router) trackLongPoll(delta int) <-chan struct{} {
+	r.longPolls.mu.Lock()
+	defer r.longPolls.mu.Unlock()
+	if r.longPolls.released == nil {
+		r.longPolls.released = make(chan struct{})
+	}
+	r.longPolls.count += delta
+	return r.longPolls.released
+}
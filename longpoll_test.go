@@ -0,0 +1,74 @@
+// Copyright 2015 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"testing"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+)
+
+func TestContextWait(t *testing.T) {
+	events := make(chan string, 1)
+	r := NewRouter()
+	r.Get("/events", func(c *Context) {
+		if event, ok := c.Wait(events, 50 * time.Millisecond); ok {
+			fmt.Fprint(c.Response, event)
+		}
+	})
+
+	serve := func() *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/events", nil)
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		return res
+	}
+
+	// event received
+	events <- "abc"
+	if res := serve(); res.Code != http.StatusOK || res.Body.String() != "abc" {
+		t.Errorf("Wait() with an event = (%v, %q), want (%v, %q)", res.Code, res.Body.String(), http.StatusOK, "abc")
+	}
+
+	// timeout
+	if res := serve(); res.Code != http.StatusNoContent || res.Body.String() != "" {
+		t.Errorf("Wait() with timeout = (%v, %q), want (%v, %q)", res.Code, res.Body.String(), http.StatusNoContent, "")
+	}
+
+	// released
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- serve()
+	}()
+	for i := 0; i < 100 && r.LongPolls() == 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if r.LongPolls() != 1 {
+		t.Errorf("LongPolls() = %v, want 1", r.LongPolls())
+	}
+	r.ReleaseLongPolls()
+	if res := <-done; res.Code != http.StatusServiceUnavailable {
+		t.Errorf("Wait() after release = %v, want %v", res.Code, http.StatusServiceUnavailable)
+	}
+	if r.LongPolls() != 0 {
+		t.Errorf("LongPolls() = %v, want 0", r.LongPolls())
+	}
+
+	// subsequent waits return immediately
+	r.ReleaseLongPolls()
+	start := time.Now()
+	if res := serve(); res.Code != http.StatusServiceUnavailable || time.Now().Sub(start) >= 50 * time.Millisecond {
+		t.Errorf("Wait() after release = %v, want %v immediately", res.Code, http.StatusServiceUnavailable)
+	}
+
+	// closed channel
+	close(events)
+	c := NewContext(httptest.NewRecorder(), nil)
+	if _, ok := c.Wait(events, time.Second); ok {
+		t.Errorf("Wait() on a closed channel returned true")
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2015 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AcceptRange represents an entry in an Accept-style HTTP header, such as Accept, Accept-Language or Accept-Charset.
+type AcceptRange struct {
+	Value   string  // the range (e.g. "text/*", "en-us", "utf-8") in lower case, without parameters
+	Quality float64 // the quality value given by the "q" parameter, defaulted to 1
+}
+
+// ParseAcceptHeader parses the value of an Accept-style HTTP header into a list of ranges.
+// The ranges are sorted by their quality values in descending order. Ranges with the same
+// quality value keep the order in which they appear in the header. Ranges with an invalid
+// quality value are ignored. For example,
+//
+//   ParseAcceptHeader("text/html;q=0.8, application/json")
+//   // returns [{application/json 1} {text/html 0.8}]
+func ParseAcceptHeader(header string) []AcceptRange {
+	var ranges []AcceptRange
+	for _, entry := range strings.Split(header, ",") {
+		parts := strings.Split(entry, ";")
+		value := strings.ToLower(strings.TrimSpace(parts[0]))
+		if value == "" {
+			continue
+		}
+		quality, valid := 1.0, true
+		for _, param := range parts[1:] {
+			param = strings.TrimSpace(param)
+			if len(param) < 2 || (param[0] != 'q' && param[0] != 'Q') || param[1] != '=' {
+				continue
+			}
+			q, err := strconv.ParseFloat(param[2:], 64)
+			if err != nil || q < 0 || q > 1 {
+				valid = false
+				break
+			}
+			quality = q
+		}
+		if valid {
+			ranges = append(ranges, AcceptRange{value, quality})
+		}
+	}
+	sort.Stable(acceptRanges(ranges))
+	return ranges
+}
+
+// Negotiate returns the content type in offers that best matches the Accept request header.
+// If the request has no Accept header, the first offer is returned.
+// An empty string is returned if none of the offers is acceptable. For example,
+//
+//   switch c.Negotiate("application/json", "application/xml") {
+//   case "application/json":
+//       // ...
+//   }
+func (c *Context) Negotiate(offers ...string) string {
+	return negotiate(c.Request.Header.Get("Accept"), offers, matchMediaRange)
+}
+
+// AcceptsLanguage returns the language tag in offers that best matches the Accept-Language request header.
+// A language range matches a tag if it equals the tag or is a prefix of it followed by "-".
+// For example, "en" matches both "en" and "en-US".
+// If the request has no Accept-Language header, the first offer is returned.
+// An empty string is returned if none of the offers is acceptable.
+func (c *Context) AcceptsLanguage(offers ...string) string {
+	return negotiate(c.Request.Header.Get("Accept-Language"), offers, matchLanguageRange)
+}
+
+// AcceptsCharset returns the charset in offers that best matches the Accept-Charset request header.
+// If the request has no Accept-Charset header, the first offer is returned.
+// An empty string is returned if none of the offers is acceptable.
+func (c *Context) AcceptsCharset(offers ...string) string {
+	return negotiate(c.Request.Header.Get("Accept-Charset"), offers, matchCharsetRange)
+}
+
+// negotiate returns the offer with the highest quality value according to the given Accept-style header.
+// The quality value of an offer is taken from the most specific range matching it, as determined
+// by the match function which returns -1 if the range does not match the offer.
+// Offers having the same quality value are preferred in the order they are given.
+func negotiate(header string, offers []string, match func(rng, offer string) int) string {
+	if len(offers) == 0 {
+		return ""
+	}
+	ranges := ParseAcceptHeader(header)
+	if len(ranges) == 0 {
+		return offers[0]
+	}
+
+	best, bestQuality := "", 0.0
+	for _, offer := range offers {
+		lower := strings.ToLower(offer)
+		quality, specificity := 0.0, -1
+		for _, rng := range ranges {
+			if s := match(rng.Value, lower); s > specificity {
+				quality, specificity = rng.Quality, s
+			}
+		}
+		if quality > bestQuality {
+			best, bestQuality = offer, quality
+		}
+	}
+	return best
+}
+
+func matchMediaRange(rng, offer string) int {
+	if semicolon := strings.Index(offer, ";"); semicolon >= 0 {
+		offer = strings.TrimSpace(offer[:semicolon])
+	}
+	switch {
+	case rng == offer:
+		return 2
+	case rng == "*/*":
+		return 0
+	case strings.HasSuffix(rng, "/*") && strings.HasPrefix(offer, rng[:len(rng)-1]):
+		return 1
+	}
+	return -1
+}
+
+func matchLanguageRange(rng, offer string) int {
+	switch {
+	case rng == "*":
+		return 0
+	case rng == offer || strings.HasPrefix(offer, rng+"-"):
+		return len(rng)
+	}
+	return -1
+}
+
+func matchCharsetRange(rng, offer string) int {
+	switch {
+	case rng == offer:
+		return 1
+	case rng == "*":
+		return 0
+	}
+	return -1
+}
+
+type acceptRanges []AcceptRange
+
+func (r acceptRanges) Len() int           { return len(r) }
+func (r acceptRanges) Less(i, j int) bool { return r[i].Quality > r[j].Quality }
+func (r acceptRanges) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
@@ -0,0 +1,113 @@
+// Copyright 2015 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"testing"
+	"net/http"
+	"fmt"
+)
+
+func TestParseAcceptHeader(t *testing.T) {
+	tests := []struct {
+		// input
+		header string
+		// output
+		ranges string
+	}{
+		{"", "[]"},
+		{"text/html", "[{text/html 1}]"},
+		{"text/html;q=0.8, application/json", "[{application/json 1} {text/html 0.8}]"},
+		{"text/*;q=0.5, TEXT/Plain;level=1, */*;q=0", "[{text/plain 1} {text/* 0.5} {*/* 0}]"},
+		{"en-US,en;q=0.9,fr;Q=0.9", "[{en-us 1} {en 0.9} {fr 0.9}]"},
+		{"a;q=2, b;q=x, c;q=0.1, , d", "[{d 1} {c 0.1}]"},
+	}
+
+	for _, tt := range tests {
+		ranges := fmt.Sprint(ParseAcceptHeader(tt.header))
+		if ranges != tt.ranges {
+			t.Errorf("ParseAcceptHeader(%q) = %v, want %v", tt.header, ranges, tt.ranges)
+		}
+	}
+}
+
+func TestContextNegotiate(t *testing.T) {
+	tests := []struct {
+		// input
+		header string
+		offers []string
+		// output
+		result string
+	}{
+		{"", []string{"application/json", "application/xml"}, "application/json"},
+		{"application/xml", []string{"application/json", "application/xml"}, "application/xml"},
+		{"application/xml;q=0.5, application/json", []string{"application/xml", "application/json"}, "application/json"},
+		{"text/*, application/json;q=0.5", []string{"application/json", "text/html"}, "text/html"},
+		{"*/*;q=0.1, text/html;q=0", []string{"text/html", "text/plain"}, "text/plain"},
+		{"text/html", []string{"application/json"}, ""},
+		{"text/html", []string{"Text/HTML; charset=utf-8"}, "Text/HTML; charset=utf-8"},
+		{"text/html", nil, ""},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept", tt.header)
+		c := NewContext(nil, req)
+		if result := c.Negotiate(tt.offers...); result != tt.result {
+			t.Errorf("Negotiate(%q, %q) = %q, want %q", tt.header, tt.offers, result, tt.result)
+		}
+	}
+}
+
+func TestContextAcceptsLanguage(t *testing.T) {
+	tests := []struct {
+		// input
+		header string
+		offers []string
+		// output
+		result string
+	}{
+		{"", []string{"en", "fr"}, "en"},
+		{"fr", []string{"en", "fr"}, "fr"},
+		{"en;q=0.8, fr-CA", []string{"en-US", "fr", "fr-CA"}, "fr-CA"},
+		{"en", []string{"en-US"}, "en-US"},
+		{"en-US", []string{"en"}, ""},
+		{"*;q=0.5, de;q=0", []string{"de", "ru"}, "ru"},
+		{"fr-ca, fr;q=0.2", []string{"fr-FR", "fr-CA"}, "fr-CA"},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept-Language", tt.header)
+		c := NewContext(nil, req)
+		if result := c.AcceptsLanguage(tt.offers...); result != tt.result {
+			t.Errorf("AcceptsLanguage(%q, %q) = %q, want %q", tt.header, tt.offers, result, tt.result)
+		}
+	}
+}
+
+func TestContextAcceptsCharset(t *testing.T) {
+	tests := []struct {
+		// input
+		header string
+		offers []string
+		// output
+		result string
+	}{
+		{"", []string{"utf-8", "iso-8859-1"}, "utf-8"},
+		{"iso-8859-1, utf-8;q=0.7", []string{"UTF-8", "ISO-8859-1"}, "ISO-8859-1"},
+		{"*;q=0.3, utf-8", []string{"koi8-r", "utf-8"}, "utf-8"},
+		{"utf-8", []string{"koi8-r"}, ""},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept-Charset", tt.header)
+		c := NewContext(nil, req)
+		if result := c.AcceptsCharset(tt.offers...); result != tt.result {
+			t.Errorf("AcceptsCharset(%q, %q) = %q, want %q", tt.header, tt.offers, result, tt.result)
+		}
+	}
+}
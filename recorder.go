@@ -0,0 +1,61 @@
+// Copyright 2015 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// responseRecorder captures the response status, content type and body, such as for recording fixtures
+// or for collecting the responses of batched sub-requests. If ResponseWriter is not nil, the response
+// is also written to it; otherwise, header must be set to hold the response headers. Like http.ResponseWriter,
+// the content type is detected from the content if it is not set when the content is written.
+type responseRecorder struct {
+	http.ResponseWriter
+	header      http.Header
+	status      int
+	contentType string
+	body        bytes.Buffer
+}
+
+func (w *responseRecorder) Header() http.Header {
+	if w.ResponseWriter != nil {
+		return w.ResponseWriter.Header()
+	}
+	return w.header
+}
+
+func (w *responseRecorder) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.contentType == "" && w.body.Len() == 0 && len(p) > 0 {
+		w.contentType = http.DetectContentType(p)
+	}
+	w.body.Write(p)
+	if w.ResponseWriter != nil {
+		return w.ResponseWriter.Write(p)
+	}
+	return len(p), nil
+}
+
+func (w *responseRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+		w.contentType = w.Header().Get("Content-Type")
+	}
+	if w.ResponseWriter != nil {
+		w.ResponseWriter.WriteHeader(status)
+	}
+}
+
+// Status returns the status code of the response.
+func (w *responseRecorder) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
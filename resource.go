@@ -0,0 +1,107 @@
+// Copyright 2015 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// resourceActions lists the standard actions of RESTful resources in the order their routes are registered.
+// It is shared by Resource, which maps controller methods of these names, and REST, which uses the handler
+// accessors to get the corresponding RESTHandlers fields.
+var resourceActions = []struct {
+	name    string
+	pattern string
+	handler func(RESTHandlers) Handler
+}{
+	{"Index", "GET ", func(h RESTHandlers) Handler { return h.Index }},
+	{"Create", "POST ", func(h RESTHandlers) Handler { return h.Create }},
+	{"Show", "GET /<id>", func(h RESTHandlers) Handler { return h.Show }},
+	{"Update", "PUT,PATCH /<id>", func(h RESTHandlers) Handler { return h.Update }},
+	{"Delete", "DELETE /<id>", func(h RESTHandlers) Handler { return h.Delete }},
+}
+
+// resourceVerbs lists the HTTP methods that may be used as prefixes of custom controller action names.
+var resourceVerbs = []string{"Get", "Post", "Put", "Patch", "Delete", "Head", "Options"}
+
+// Resource adds a group of RESTful routes whose handlers are the methods of the given controller.
+// The controller is usually a pointer to a struct. Its methods are mapped to routes as follows:
+//
+//   Index       GET /users
+//   Create      POST /users
+//   Show        GET /users/<id>
+//   Update      PUT,PATCH /users/<id>
+//   Delete      DELETE /users/<id>
+//   GetAvatar   GET /users/<id>/avatar
+//
+// The last row shows how a custom action is mapped: a method whose name starts with an HTTP method
+// (Get, Post, Put, Patch, Delete, Head or Options) is mapped to a route under the individual resource,
+// with the rest of the name converted into a dash-separated path segment (e.g. GetRecentPosts is
+// mapped to "GET /users/<id>/recent-posts"). Methods that are not named this way are not mapped, and neither
+// are methods that cannot be used as handlers, such as a helper "GetDB() (*sql.DB, error)" returning two values.
+//
+// The controller methods are called like any other handlers, which means their parameter values
+// are injected by Context. For example,
+//
+//   type UserController struct{}
+//
+//   func (uc *UserController) Show(c *routing.Context) string {
+//       return "user " + c.Params["id"]
+//   }
+//
+//   router.Resource("/users", &UserController{})
+//
+// The optional handlers are associated with the child router that serves the resource routes.
+// The child router is returned to allow adding more routes to the resource.
+func (r *Router) Resource(pattern string, controller interface{}, handlers ...Handler) *Router {
+	router := NewChildRouter(pattern, handlers)
+	router.Parent = r
+	r.Routes = append(r.Routes, router)
+
+	value := reflect.ValueOf(controller)
+	for _, action := range resourceActions {
+		if method := value.MethodByName(action.name); method.IsValid() {
+			router.To(action.pattern, method.Interface())
+		}
+	}
+
+	t := value.Type()
+	for i := 0; i < t.NumMethod(); i++ {
+		handler := value.Method(i).Interface()
+		if pattern := customActionPattern(t.Method(i).Name); pattern != "" && checkHandler(handler) == "" {
+			router.To(pattern, handler)
+		}
+	}
+
+	return router
+}
+
+// customActionPattern returns the route pattern for the custom controller action with the given name.
+// An empty string is returned if the name does not represent a custom action.
+func customActionPattern(name string) string {
+	for _, verb := range resourceVerbs {
+		if len(name) > len(verb) && strings.HasPrefix(name, verb) && unicode.IsUpper(rune(name[len(verb)])) {
+			return strings.ToUpper(verb) + " /<id>/" + dashName(name[len(verb):])
+		}
+	}
+	return ""
+}
+
+// dashName converts a CamelCase name into a lower case dash-separated name (e.g. "RecentPosts" to "recent-posts").
+func dashName(name string) string {
+	var result []rune
+	for i, c := range name {
+		if unicode.IsUpper(c) {
+			if i > 0 {
+				result = append(result, '-')
+			}
+			c = unicode.ToLower(c)
+		}
+		result = append(result, c)
+	}
+	return string(result)
+}
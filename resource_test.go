@@ -0,0 +1,90 @@
+// Copyright 2015 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"testing"
+)
+
+type userController struct {
+	prefix string
+}
+
+func (uc *userController) Index() string {
+	return uc.prefix + "index"
+}
+
+func (uc *userController) Show(c *Context) string {
+	return uc.prefix + "show:" + c.Params["id"]
+}
+
+func (uc *userController) Create() string {
+	return uc.prefix + "create"
+}
+
+func (uc *userController) Update(c *Context) string {
+	return uc.prefix + "update:" + c.Params["id"]
+}
+
+func (uc *userController) GetAvatar(c *Context) string {
+	return uc.prefix + "avatar:" + c.Params["id"]
+}
+
+func (uc *userController) PostRecentPosts(c *Context) string {
+	return uc.prefix + "recent:" + c.Params["id"]
+}
+
+func (uc *userController) Getter() string {
+	return "getter"
+}
+
+func (uc *userController) GetDB() (string, error) {
+	return "db", nil
+}
+
+func TestCustomActionPattern(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+	}{
+		{"GetAvatar", "GET /<id>/avatar"},
+		{"PostRecentPosts", "POST /<id>/recent-posts"},
+		{"DeleteAll", "DELETE /<id>/all"},
+		{"Get", ""},
+		{"Delete", ""},
+		{"Getter", ""},
+		{"Helper", ""},
+	}
+
+	for _, tt := range tests {
+		if pattern := customActionPattern(tt.name); pattern != tt.pattern {
+			t.Errorf("customActionPattern(%q) = %q, want %q", tt.name, pattern, tt.pattern)
+		}
+	}
+}
+
+func TestRouterResource(t *testing.T) {
+	r := NewRouter()
+	r.Resource("/users", &userController{"user:"})
+	r.Group("/admin", func(r *Router) {
+		r.Resource("/users", &userController{"admin:"}, handleNext("admin"))
+	})
+
+	tests := []dispatchTest{
+		{"GET", "/users", "user:index"},
+		{"POST", "/users", "user:create"},
+		{"GET", "/users/12", "user:show:12"},
+		{"PUT", "/users/12", "user:update:12"},
+		{"PATCH", "/users/12", "user:update:12"},
+		{"DELETE", "/users/12", ""},
+		{"GET", "/users/12/avatar", "user:avatar:12"},
+		{"POST", "/users/12/recent-posts", "user:recent:12"},
+		{"GET", "/users/12/er", ""},
+		{"GET", "/users/12/d-b", ""},
+		{"GET", "/admin/users/3", "<adminadmin:show:3admin>"},
+	}
+
+	runDispatchTests(t, tests, r)
+}
@@ -0,0 +1,87 @@
+// Copyright 2015 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"sort"
+	"strings"
+)
+
+// RESTHandlers specifies the handlers of the standard actions of a RESTful resource, as well as its nested resources.
+// The route of an action is not registered if its handler is nil or if the action is listed in Skip.
+type RESTHandlers struct {
+	Index   Handler                 // handler for "GET /users"
+	Create  Handler                 // handler for "POST /users"
+	Show    Handler                 // handler for "GET /users/<id>"
+	Update  Handler                 // handler for "PUT,PATCH /users/<id>"
+	Delete  Handler                 // handler for "DELETE /users/<id>"
+
+	// Skip lists the actions (e.g. "delete") whose routes should not be registered even if their handlers are given.
+	Skip    []string
+	// Nested specifies the resources nested under an individual resource, indexed by their names.
+	// For example, "posts" nested under "users" is served under "/users/<user_id>/posts".
+	Nested  map[string]RESTHandlers
+	// IDParam is the name of the URL parameter that captures the resource ID in the routes of the nested resources.
+	// It is defaulted to the resource name with the trailing "s" removed, followed by "_id" (e.g. "user_id" for "users").
+	IDParam string
+}
+
+// REST adds the routes of a RESTful resource with the given name, which is used as the URL path prefix of the routes.
+// The standard actions are mapped to routes as follows, where the route names are shown in parenthesis:
+//
+//   GET /users                   Index   (users.index)
+//   POST /users                  Create  (users.create)
+//   GET /users/<id>              Show    (users.show)
+//   PUT,PATCH /users/<id>        Update  (users.update)
+//   DELETE /users/<id>           Delete  (users.delete)
+//
+// The routes of the nested resources are added in the same way under an individual resource. For example,
+// the Show action of "posts" nested under "users" is mapped to "GET /users/<user_id>/posts/<id>", and its route
+// is named "users.posts.show". The route names can be used to build URLs by calling Router.URL().
+//
+// The child router that serves the resource routes is returned to allow adding more routes to the resource.
+func (r *Router) REST(name string, handlers RESTHandlers) *Router {
+	return r.addREST(name, name, handlers)
+}
+
+// addREST adds the routes of a RESTful resource whose routes are named with the given prefix.
+func (r *Router) addREST(prefix, name string, handlers RESTHandlers) *Router {
+	router := NewChildRouter("/" + name, nil)
+	router.Parent = r
+	r.Routes = append(r.Routes, router)
+
+	skipped := make(map[string]bool)
+	for _, action := range handlers.Skip {
+		skipped[strings.ToLower(action)] = true
+	}
+
+	for _, action := range resourceActions {
+		name := strings.ToLower(action.name)
+		if handler := action.handler(handlers); handler != nil && !skipped[name] {
+			router.To(action.pattern, handler).Name = prefix + "." + name
+		}
+	}
+
+	if len(handlers.Nested) > 0 {
+		idParam := handlers.IDParam
+		if idParam == "" {
+			idParam = strings.TrimSuffix(name, "s") + "_id"
+		}
+		member := NewChildRouter("/<" + idParam + ">", nil)
+		member.Parent = router
+		router.Routes = append(router.Routes, member)
+
+		var names []string
+		for nested := range handlers.Nested {
+			names = append(names, nested)
+		}
+		sort.Strings(names)
+		for _, nested := range names {
+			member.addREST(prefix + "." + nested, nested, handlers.Nested[nested])
+		}
+	}
+
+	return router
+}
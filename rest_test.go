@@ -0,0 +1,70 @@
+// Copyright 2015 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"testing"
+)
+
+func TestRouterREST(t *testing.T) {
+	r := NewRouter()
+	r.REST("users", RESTHandlers{
+		Index:  handle("users.index"),
+		Create: handle("users.create"),
+		Show:   handle("users.show"),
+		Update: handle("users.update"),
+		Delete: handle("users.delete"),
+		Skip:   []string{"Delete"},
+		Nested: map[string]RESTHandlers{
+			"posts": {
+				Index: handle("posts.index"),
+				Show:  handle("posts.show"),
+				Nested: map[string]RESTHandlers{
+					"comments": {
+						Show: handle("comments.show"),
+					},
+				},
+			},
+			"tags": {
+				Index: handle("tags.index"),
+			},
+		},
+	})
+
+	tests := []dispatchTest{
+		{"GET", "/users", "<users.index>"},
+		{"POST", "/users", "<users.create>"},
+		{"GET", "/users/1", "<users.show>{id:1,}"},
+		{"PUT", "/users/1", "<users.update>{id:1,}"},
+		{"PATCH", "/users/1", "<users.update>{id:1,}"},
+		{"DELETE", "/users/1", ""},
+		{"GET", "/users/1/posts", "<posts.index>{user_id:1,}"},
+		{"POST", "/users/1/posts", ""},
+		{"GET", "/users/1/posts/2", "<posts.show>{id:2,user_id:1,}"},
+		{"GET", "/users/1/posts/2/comments/3", "<comments.show>{id:3,post_id:2,user_id:1,}"},
+		{"GET", "/users/1/tags", "<tags.index>{user_id:1,}"},
+	}
+
+	runDispatchTests(t, tests, r)
+
+	urls := []struct {
+		name   string
+		params map[string]string
+		url    string
+	}{
+		{"users.index", nil, "/users"},
+		{"users.show", map[string]string{"id": "1"}, "/users/1"},
+		{"users.delete", map[string]string{"id": "1"}, ""},
+		{"users.posts.show", map[string]string{"user_id": "1", "id": "2"}, "/users/1/posts/2"},
+		{"users.posts.comments.show", map[string]string{"user_id": "1", "post_id": "2", "id": "3"}, "/users/1/posts/2/comments/3"},
+		{"users.tags.index", map[string]string{"user_id": "1"}, "/users/1/tags"},
+	}
+
+	for _, tt := range urls {
+		if url := r.URL(tt.name, tt.params); url != tt.url {
+			t.Errorf("URL(%q, %v) = %q, want %q", tt.name, tt.params, url, tt.url)
+		}
+	}
+}
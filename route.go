@@ -5,6 +5,10 @@
 package routing
 
 import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
 	"regexp"
 	"fmt"
 	"strings"
@@ -14,12 +18,17 @@ import (
 // If a route matches the current HTTP request, the associated handlers will be invoked.
 // A route matches a request only if it matches both the HTTP method and the URL path of the current request.
 type Route struct {
-	Methods  map[string]bool // HTTP methods
-	Pattern  string          // URL path to be matched
-	Handlers []Handler       // handlers associated with this route
+	Name      string                // the name of the route, used to build URLs by Router.URL()
+	Methods   map[string]bool       // HTTP methods
+	Pattern   string                // URL path to be matched
+	Handlers  []Handler             // handlers associated with this route
+	Defaults  map[string]string     // default values of the URL parameters that are absent from the matched URL path
 
-	err      bool            // whether this route is for handling errors
-	regex    *regexp.Regexp  // parsed regex of pattern
+	err       bool                  // whether this route is for handling errors
+	regex     *regexp.Regexp        // parsed regex of pattern
+	consumes  []string              // content types accepted for the request body
+	maxLength int64                 // maximum length of the request body, 0 meaning unlimited
+	guards    []func(*Context) bool // conditions that must be met for the route to handle a request
 }
 
 // RoutePatternError describes the route pattern which is of invalid format.
@@ -48,6 +57,10 @@ var (
 //     /users                // matches "/users"
 //     /users/<id:\d+>       // matches "/users/123"
 //     GET,POST /users       // matches "/users" for GET or POST only
+//     /posts(/<page:\d+>)?  // matches "/posts" and "/posts/2"
+//
+// When a part of the pattern is optional, as in the last example above, call Default()
+// to declare the parameter value to be used when that part is absent from the URL path.
 func NewRoute(pattern string, handlers []Handler) *Route {
 	matches := routeRegex.FindStringSubmatch(pattern)
 	if len(matches) != 3 {
@@ -57,6 +70,7 @@ func NewRoute(pattern string, handlers []Handler) *Route {
 	route := Route{
 		Methods: make(map[string]bool),
 		Pattern: matches[2],
+		Defaults: make(map[string]string),
 	}
 
 	if len(matches[1]) > 0 {
@@ -75,6 +89,71 @@ func NewRoute(pattern string, handlers []Handler) *Route {
 	return &route
 }
 
+// Default declares the default value of the named URL parameter.
+// The default value is used to populate Context.Params when the parameter is absent from
+// the matched URL path, which is the case when it belongs to an optional part of the pattern.
+// The same route object is returned to allow further method chaining. For example,
+//
+//   router.Get(`/posts(/<page:\d+>)?`, func() { }).Default("page", "1")
+func (r *Route) Default(name, value string) *Route {
+	r.Defaults[name] = value
+	return r
+}
+
+// Consumes declares the content types that the route accepts for the request body, such as "application/json".
+// A content type may use a wildcard subtype, such as "text/*". If a request with a body has a Content-Type
+// header that matches none of the declared types, an HTTPError with the status http.StatusUnsupportedMediaType (415)
+// will be triggered before any handler of the route is called.
+// The same route object is returned to allow further method chaining.
+func (r *Route) Consumes(types ...string) *Route {
+	for _, t := range types {
+		r.consumes = append(r.consumes, strings.ToLower(t))
+	}
+	return r
+}
+
+// MaxLength declares the maximum length (in bytes) of the request body that the route accepts.
+// If a request declares a longer Content-Length, an HTTPError with the status http.StatusRequestEntityTooLarge (413)
+// will be triggered before any handler of the route is called. If a request does not declare its Content-Length,
+// the status will be http.StatusLengthRequired (411), unless its body is already limited by RequestDecompressor,
+// in which case reading more than n bytes of the decompressed body will fail with an HTTPError of the status 413.
+// The same route object is returned to allow further method chaining.
+func (r *Route) MaxLength(n int64) *Route {
+	r.maxLength = n
+	return r
+}
+
+// When adds a condition that must be met for the route to handle a request. The condition is evaluated
+// when the route matches a request. If it returns false, the route is skipped as if it did not match,
+// and the request is passed to the next matching route. This allows routes to be enabled per environment,
+// per tenant or by feature flags. For example,
+//
+//   router.Get("/beta", func() { }).When(func(c *routing.Context) bool {
+//       return flags.Enabled("beta", c.Request)
+//   })
+//
+// When may be called multiple times to add more conditions, all of which must be met. If a condition panics,
+// the error is handled like that of a handler: it is stored in Context.Error and the error handlers are called.
+// The same route object is returned to allow further method chaining.
+func (r *Route) When(condition func(*Context) bool) *Route {
+	r.guards = append(r.guards, condition)
+	return r
+}
+
+// clone returns a copy of the route that shares no maps or slices with the original route.
+func (r *Route) clone() *Route {
+	route := *r
+	route.Methods = make(map[string]bool)
+	for method := range r.Methods {
+		route.Methods[method] = true
+	}
+	route.Defaults = copyParams(r.Defaults)
+	route.Handlers = append([]Handler(nil), r.Handlers...)
+	route.consumes = append([]string(nil), r.consumes...)
+	route.guards = append([]func(*Context) bool(nil), r.guards...)
+	return &route
+}
+
 // Match checks if the route matches the specified HTTP method and URL path.
 func (r *Route) Match(method, path string) (bool, string, map[string]string) {
 	if len(r.Methods) > 0 && !r.Methods[method] {
@@ -86,11 +165,14 @@ func (r *Route) Match(method, path string) (bool, string, map[string]string) {
 // MatchPath checks if the route matches the specified URL path
 func (r *Route) MatchPath(path string) (bool, string, map[string]string) {
 	if r.regex == nil {
-		return path == r.Pattern, path, nil
+		if path != r.Pattern {
+			return false, path, nil
+		}
+		return true, path, r.applyDefaults(nil)
 	}
 
 	if r.Pattern == ".*" {
-		return true, path, nil
+		return true, path, r.applyDefaults(nil)
 	}
 
 	matches := r.regex.FindStringSubmatch(path)
@@ -105,7 +187,23 @@ func (r *Route) MatchPath(path string) (bool, string, map[string]string) {
 		}
 	}
 
-	return true, path, params
+	return true, path, r.applyDefaults(params)
+}
+
+// applyDefaults fills in the declared default values for the parameters that are missing or empty.
+func (r *Route) applyDefaults(params map[string]string) map[string]string {
+	if len(r.Defaults) == 0 {
+		return params
+	}
+	if params == nil {
+		params = make(map[string]string)
+	}
+	for name, value := range r.Defaults {
+		if params[name] == "" {
+			params[name] = value
+		}
+	}
+	return params
 }
 
 // Dispatch invokes the handlers associated with this route.
@@ -113,13 +211,42 @@ func (r *Route) Dispatch(method, path string, c *Context) {
 	index := 0
 	oldNext := c.Next
 
+	if len(r.guards) > 0 && r.err == (c.Error != nil) {
+		// evaluate the conditions like a handler, so that a panic is recovered and handled as an error
+		met, evaluated := true, false
+		callHandler(c, func(c *Context) {
+			for _, guard := range r.guards {
+				if met = guard(c); !met {
+					break
+				}
+			}
+			evaluated = true
+		})
+		if !evaluated {
+			// the control has been passed to the next route when the panic was recovered
+			return
+		}
+		if !met {
+			oldNext()
+			return
+		}
+	}
+
+	handlers := r.Handlers
+	if !r.err && (len(r.consumes) > 0 || r.maxLength > 0) {
+		handlers = append([]Handler{r.checkRequest}, handlers...)
+	}
+	if !r.err && c.Error == nil {
+		c.Route = r
+	}
+
 	c.Next = func() {
-		if index < len(r.Handlers) && (r.err == (c.Error != nil)) {
-			handler := r.Handlers[index]
+		if index < len(handlers) && (r.err == (c.Error != nil)) {
+			handler := handlers[index]
 			index++
 			callHandler(c, handler)
 		} else {
-			index = len(r.Handlers)
+			index = len(handlers)
 			c.Next = oldNext
 			oldNext()
 		}
@@ -128,6 +255,109 @@ func (r *Route) Dispatch(method, path string, c *Context) {
 	c.Next()
 }
 
+// checkRequest is a handler that enforces the request preconditions declared by Consumes() and MaxLength().
+func (r *Route) checkRequest(c *Context) {
+	if r.maxLength > 0 {
+		if c.Request.ContentLength < 0 {
+			if _, ok := c.Request.Body.(*limitedBody); !ok {
+				c.Panic(http.StatusLengthRequired)
+			}
+			// the body is already limited (e.g. by RequestDecompressor), so its length is checked while reading it
+			c.Request.Body = &limitedBody{ReadCloser: c.Request.Body, limit: r.maxLength}
+		}
+		if c.Request.ContentLength > r.maxLength {
+			c.Panic(http.StatusRequestEntityTooLarge)
+		}
+	}
+
+	if len(r.consumes) > 0 && c.Request.ContentLength != 0 {
+		contentType := strings.ToLower(c.Request.Header.Get("Content-Type"))
+		accepted := false
+		for _, t := range r.consumes {
+			if matchMediaRange(t, contentType) >= 0 {
+				accepted = true
+				break
+			}
+		}
+		if !accepted {
+			c.Panic(http.StatusUnsupportedMediaType)
+		}
+	}
+
+	c.Next()
+}
+
+// buildPath builds a URL path from the pattern by replacing the "<name:pattern>" tokens with the URL-escaped
+// parameter values and by removing the regexp escapes (e.g. "\\."). An optional group, such as "(/<page:\\d+>)?",
+// is kept only if all of its parameters are given. False is returned if the pattern contains other regexp syntax
+// that cannot be turned into a path.
+func buildPath(pattern string, params map[string]string) (string, bool) {
+	path, end, _, ok := buildPathPart(pattern, 0, params)
+	return path, ok && end == len(pattern)
+}
+
+// buildPathPart builds the part of a URL path from the pattern starting at index i until the end of
+// the pattern or the closing parenthesis of the current group. It returns the built part, the index where
+// it stops, whether all parameters in the part are given, and whether the part can be built.
+func buildPathPart(pattern string, i int, params map[string]string) (string, int, bool, bool) {
+	var buf bytes.Buffer
+	complete := true
+	for i < len(pattern) {
+		switch c := pattern[i]; c {
+		case '<':
+			end := strings.IndexByte(pattern[i:], '>')
+			if end < 0 {
+				return "", i, false, false
+			}
+			matches := paramInternalRegex.FindStringSubmatch(pattern[i+1 : i+end])
+			if len(matches) < 3 {
+				return "", i, false, false
+			}
+			value, ok := params[matches[1]]
+			complete = complete && ok && value != ""
+			buf.WriteString(url.PathEscape(value))
+			i += end + 1
+		case '\\':
+			if i + 1 >= len(pattern) || isAlphanumeric(pattern[i+1]) {
+				return "", i, false, false
+			}
+			buf.WriteByte(pattern[i+1])
+			i += 2
+		case '(':
+			start := i + 1
+			if strings.HasPrefix(pattern[start:], "?:") {
+				start += 2
+			}
+			part, end, partComplete, ok := buildPathPart(pattern, start, params)
+			if !ok || end >= len(pattern) || pattern[end] != ')' {
+				return "", i, false, false
+			}
+			i = end + 1
+			if i < len(pattern) && pattern[i] == '?' {
+				i++
+				if partComplete {
+					buf.WriteString(part)
+				}
+			} else {
+				buf.WriteString(part)
+				complete = complete && partComplete
+			}
+		case ')':
+			return buf.String(), i, complete, true
+		case '.', '*', '+', '?', '[', ']', '{', '}', '|', '^', '$':
+			return "", i, false, false
+		default:
+			buf.WriteByte(c)
+			i++
+		}
+	}
+	return buf.String(), i, complete, true
+}
+
+func isAlphanumeric(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
 // parseParamPattern converts "<name:pattern>" tokens in the pattern into named subpattern in a regexp.
 func parseParamPattern(pattern string) string {
 	return paramRegex.ReplaceAllStringFunc(pattern, func(m string) string {
@@ -142,3 +372,28 @@ func parseParamPattern(pattern string) string {
 		}
 	})
 }
+
+// limitedBody reads a request body up to the limit. Reading beyond the limit fails with an HTTPError
+// of the status http.StatusRequestEntityTooLarge (413).
+type limitedBody struct {
+	io.ReadCloser
+	limit int64
+	read  int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.read >= b.limit {
+		// check if there is more data beyond the limit
+		var buf [1]byte
+		if n, _ := b.ReadCloser.Read(buf[:]); n > 0 {
+			return 0, NewHTTPError(http.StatusRequestEntityTooLarge)
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > b.limit - b.read {
+		p = p[:b.limit - b.read]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	return n, err
+}
@@ -7,6 +7,9 @@ package routing
 import (
 	"testing"
 	"strings"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 )
 
 func TestNewRoute(t *testing.T) {
@@ -158,3 +161,76 @@ func TestRouteMatchParams(t *testing.T) {
 	}
 }
 
+
+func TestRouteDefaults(t *testing.T) {
+	tests := []struct {
+		// input
+		pattern  string
+		path     string
+		// output
+		matching bool
+		params   map[string]string
+	}{
+		{"/posts(/<page:\\d+>)?", "/posts", true, map[string]string{"page": "1", "sort": "id"}},
+		{"/posts(/<page:\\d+>)?", "/posts/3", true, map[string]string{"page": "3", "sort": "id"}},
+		{"/posts(/<page:\\d+>)?", "/posts/", false, map[string]string{}},
+		{"/posts(/<page:\\d+>)?", "/posts/abc", false, map[string]string{}},
+		{"/posts", "/posts", true, map[string]string{"page": "1", "sort": "id"}},
+	}
+
+	for _, tt := range tests {
+		r := NewRoute(tt.pattern, nil).Default("page", "1").Default("sort", "id")
+		matching, _, params := r.Match("GET", tt.path)
+		if matching != tt.matching {
+			t.Errorf("newRoute(%q).Match(%q, %q).matching = %v, want %v", tt.pattern, "GET", tt.path, matching, tt.matching)
+		}
+		if matching && fmtMap(params) != fmtMap(tt.params) {
+			t.Errorf("newRoute(%q).Match(%q, %q).params = %v, want %v", tt.pattern, "GET", tt.path, params, tt.params)
+		}
+	}
+}
+
+func TestRoutePreconditions(t *testing.T) {
+	r := NewRouter()
+	r.Post("/users", handle("users")).Consumes("application/json", "text/*").MaxLength(10)
+	r.Get("/users", handle("users/get")).Consumes("application/json")
+	r.Error(func(c *Context) {
+		if err, ok := c.Error.(HTTPError); ok {
+			fmt.Fprintf(c.Response, "<err:%v>", err.Code())
+		}
+	})
+
+	tests := []struct {
+		// input
+		method      string
+		contentType string
+		body        string
+		chunked     bool
+		// output
+		result      string
+	}{
+		{"POST", "application/json", `{"a":1}`, false, "<users>"},
+		{"POST", "Application/JSON; charset=utf-8", `{"a":1}`, false, "<users>"},
+		{"POST", "text/plain", "abc", false, "<users>"},
+		{"POST", "application/xml", "<a/>", false, "<err:415>"},
+		{"POST", "", "abc", false, "<err:415>"},
+		{"POST", "application/json", `{"a":"long value"}`, false, "<err:413>"},
+		{"POST", "application/json", `{"a":1}`, true, "<err:411>"},
+		{"GET", "", "", false, "<users/get>"},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest(tt.method, "/users", strings.NewReader(tt.body))
+		if tt.contentType != "" {
+			req.Header.Set("Content-Type", tt.contentType)
+		}
+		if tt.chunked {
+			req.ContentLength = -1
+		}
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		if res.Body.String() != tt.result {
+			t.Errorf("Dispatch(%q, %q, %q) = %q, want %q", tt.method, tt.contentType, tt.body, res.Body.String(), tt.result)
+		}
+	}
+}
@@ -15,6 +15,10 @@ import (
 	"reflect"
 	"fmt"
 	"os"
+	"net/url"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
 )
 
 // Handler is the type of the functions that can be associated with a router or route.
@@ -52,14 +56,20 @@ type Routable interface {
 // And call Error() to register error handlers that are only called when the router
 // recovers a panic from a handler.
 type Router struct {
-	Parent   *Router         // the parent router
-	Routes   []Routable      // routes and child routers associated with this router
+	Parent       *Router         // the parent router
+	Routes       []Routable      // routes and child routers associated with this router
 
-	Methods  map[string]bool // the HTTP methods used to match the current HTTP method
-	Pattern  string          // the pattern used to match request URL path
-	Handlers []Handler       // handlers associated with the router
+	Methods      map[string]bool // the HTTP methods used to match the current HTTP method
+	Pattern      string          // the pattern used to match request URL path
+	Handlers     []Handler       // handlers associated with the router
 
-	regex    *regexp.Regexp  // the compiled regexp of the pattern
+	MatrixParams bool            // whether to parse matrix parameters (e.g. "/cars;color=red") out of the URL path
+	Debug        bool            // whether to collect the diagnostic information of errors (see DebugErrorHandler)
+
+	regex        *regexp.Regexp  // the compiled regexp of the pattern
+	longPolls    longPolls       // the requests waiting in Context.Wait()
+	snapshot     atomic.Value    // the *Snapshot installed by Swap()
+	swapMutex    sync.Mutex      // the mutex serializing the calls of Swap()
 }
 
 // DataWriter writes the given data to response.
@@ -114,8 +124,25 @@ func NewChildRouter(pattern string, handlers []Handler) *Router {
 
 // ServeHTTP dispatches the request to the handlers of the matching route(s).
 // ServeHTTP is the method required by http.Handler
+//
+// If a snapshot is installed by Swap(), the request is dispatched to the routes of the snapshot,
+// and the MatrixParams and Debug settings of the snapshot are used instead of those of the router.
+//
+// If MatrixParams is true, matrix parameters are removed from the URL path before it is matched
+// against the routes, and they are made available through Context.Matrix.
 func (r *Router) ServeHTTP(res http.ResponseWriter, req *http.Request) {
-	r.Dispatch(req.Method, req.URL.Path, NewContext(res, req))
+	routes := r
+	if s := r.Snapshot(); s != nil {
+		routes = s.router
+	}
+	c := NewContext(res, req)
+	c.router = r
+	c.debugging = routes.Debug
+	path := req.URL.Path
+	if routes.MatrixParams {
+		path, c.Matrix = parseMatrixParams(req.URL.EscapedPath())
+	}
+	routes.Dispatch(req.Method, path, c)
 }
 
 // Group adds a set of routes that are grouped together by a common URL path prefix.
@@ -222,12 +249,77 @@ func (r *Router) Options(pattern string, handlers ...Handler) *Route {
 	return r.AddRoute(NewRoute("OPTIONS " + pattern, handlers))
 }
 
+// Clone returns a deep copy of the router, including its routes and child routers. The copy and the original router
+// can then be modified independently, although they share the handlers. The Parent of the copy is nil, and the routes
+// that are neither Route nor Router are shared rather than copied.
+func (r *Router) Clone() *Router {
+	router := &Router{
+		Methods: make(map[string]bool),
+		Pattern: r.Pattern,
+		Handlers: append([]Handler(nil), r.Handlers...),
+		MatrixParams: r.MatrixParams,
+		Debug: r.Debug,
+		regex: r.regex,
+	}
+	for method := range r.Methods {
+		router.Methods[method] = true
+	}
+	for _, routable := range r.Routes {
+		switch rt := routable.(type) {
+		case *Route:
+			routable = rt.clone()
+		case *Router:
+			child := rt.Clone()
+			child.Parent = router
+			routable = child
+		}
+		router.Routes = append(router.Routes, routable)
+	}
+	return router
+}
+
 // AddRoute adds a route to the router. The same route object is returned to allow further method chaining.
 func (r *Router) AddRoute(route *Route) *Route {
 	r.Routes = append(r.Routes, route)
 	return route
 }
 
+// URL builds the URL path of the route with the given name, which can be found anywhere in the routing tree
+// under this router. The "<name:pattern>" tokens in the route pattern and the patterns of the child routers
+// containing the route are replaced with the corresponding URL-escaped values in params. An optional part
+// of the pattern, such as "(/<page:\\d+>)?", is only included if all of its parameters are given. For example,
+//
+//   router.Get("/users/<id:\\d+>", func() { }).Name = "user"
+//   router.URL("user", map[string]string{"id": "123"})  // returns "/users/123"
+//
+// An empty string is returned if the route cannot be found, or if its pattern contains regular expression
+// syntax other than parameter tokens, optional groups and escaped characters, such as "/files/.*".
+func (r *Router) URL(name string, params map[string]string) string {
+	if pattern, ok := r.findPattern(name); ok {
+		if path, ok := buildPath(pattern, params); ok {
+			return path
+		}
+	}
+	return ""
+}
+
+// findPattern returns the full pattern of the route with the given name, including the patterns of the routers containing it.
+func (r *Router) findPattern(name string) (string, bool) {
+	for _, routable := range r.Routes {
+		switch rt := routable.(type) {
+		case *Route:
+			if rt.Name == name {
+				return r.Pattern + rt.Pattern, true
+			}
+		case *Router:
+			if pattern, ok := rt.findPattern(name); ok {
+				return r.Pattern + pattern, true
+			}
+		}
+	}
+	return "", false
+}
+
 // Match checks if the router matches the specified HTTP method and URL path.
 func (r *Router) Match(method, path string) (bool, string, map[string]string) {
 	if len(r.Methods) > 0 && !r.Methods[method] {
@@ -318,6 +410,51 @@ func (r *Router) Dispatch(method, path string, context *Context) {
 	nextFunc()
 }
 
+// parseMatrixParams removes matrix parameters from the given escaped URL path (see url.URL.EscapedPath).
+// It returns the cleaned and unescaped path and the matrix parameters keyed by the path segments they are attached to.
+// For example, "/cars;color=red;year=2020/doors" results in "/cars/doors" and {"cars": {"color": ["red"], "year": ["2020"]}}.
+// The path is split before it is unescaped, so that an escaped ";" or "=" (e.g. "%3B") is not treated as a separator.
+func parseMatrixParams(path string) (string, map[string]url.Values) {
+	matrix := make(map[string]url.Values)
+	if strings.Index(path, ";") < 0 {
+		return unescapePath(path), matrix
+	}
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		parts := strings.Split(segment, ";")
+		segments[i] = unescapePath(parts[0])
+		if len(parts) == 1 {
+			continue
+		}
+		values, ok := matrix[segments[i]]
+		if !ok {
+			values = make(url.Values)
+			matrix[segments[i]] = values
+		}
+		for _, part := range parts[1:] {
+			if part == "" {
+				continue
+			}
+			if eq := strings.Index(part, "="); eq >= 0 {
+				values.Add(unescapePath(part[:eq]), unescapePath(part[eq+1:]))
+			} else {
+				values.Add(unescapePath(part), "")
+			}
+		}
+	}
+
+	return strings.Join(segments, "/"), matrix
+}
+
+// unescapePath unescapes a part of a URL path. The part is returned unchanged if it is not validly escaped.
+func unescapePath(s string) string {
+	if v, err := url.PathUnescape(s); err == nil {
+		return v
+	}
+	return s
+}
+
 func copyParams(params map[string]string) map[string]string {
 	r := make(map[string]string)
 	for k, v := range params {
@@ -328,19 +465,31 @@ func copyParams(params map[string]string) map[string]string {
 
 func validateHandlers(handlers []Handler) {
 	for _, handler := range handlers {
-		t := reflect.TypeOf(handler)
-		if t.Kind() != reflect.Func {
-			panic("a handler must be a callable function")
-		}
-		if t.NumOut() > 1 {
-			panic("a handler can return at most one value")
+		if err := checkHandler(handler); err != "" {
+			panic(err)
 		}
 	}
 }
 
+// checkHandler returns a message describing why the given value cannot be used as a handler,
+// or an empty string if it can.
+func checkHandler(handler Handler) string {
+	t := reflect.TypeOf(handler)
+	if t == nil || t.Kind() != reflect.Func {
+		return "a handler must be a callable function"
+	}
+	if t.NumOut() > 1 {
+		return "a handler can return at most one value"
+	}
+	return ""
+}
+
 func callHandler(c *Context, fn Handler) {
 	defer func() {
 		if err := recover(); err != nil {
+			if c.Error == nil && c.debugging {
+				c.debug = newDebugInfo(c, err, debug.Stack())
+			}
 			c.Error = err
 			c.NextRoute()
 		}
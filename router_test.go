@@ -425,6 +425,210 @@ func TestDispatchParams(t *testing.T) {
 	runDispatchTests(t, tests, r)
 }
 
+func TestDispatchDefaults(t *testing.T) {
+	r := NewRouter()
+	r.Get("/posts(/<page:\\d+>)?", handle("posts")).Default("page", "1")
+	r.Group("/users/<id:\\d+>", func(r *Router) {
+		r.Get("(/<tab:[a-z]+>)?", handle("users")).Default("tab", "profile")
+	})
+
+	tests := []dispatchTest{
+		{"GET", "/posts", "<posts>{page:1,}"},
+		{"GET", "/posts/2", "<posts>{page:2,}"},
+		{"GET", "/posts/", ""},
+		{"GET", "/users/12", "<users>{id:12,tab:profile,}"},
+		{"GET", "/users/12/posts", "<users>{id:12,tab:posts,}"},
+	}
+
+	runDispatchTests(t, tests, r)
+}
+
+func TestParseMatrixParams(t *testing.T) {
+	tests := []struct {
+		// input
+		path   string
+		// output
+		clean  string
+		matrix string
+	}{
+		{"", "", "{}"},
+		{"/cars/doors", "/cars/doors", "{}"},
+		{"/cars;color=red;year=2020/doors", "/cars/doors", "{cars:{color:[red],year:[2020]}}"},
+		{"/cars;color=red;color=blue/doors;x", "/cars/doors", "{cars:{color:[red blue]},doors:{x:[]}}"},
+		{"/cars;;color=/doors;", "/cars/doors", "{cars:{color:[]},doors:{}}"},
+		{"/cars%20x/doors", "/cars x/doors", "{}"},
+		{"/cars%3Bx;color=r%3Dd%20x;colo%72=blue/doors", "/cars;x/doors", "{cars;x:{color:[r=d x blue]}}"},
+	}
+
+	for _, tt := range tests {
+		clean, matrix := parseMatrixParams(tt.path)
+		if clean != tt.clean {
+			t.Errorf("parseMatrixParams(%q).path = %q, want %q", tt.path, clean, tt.clean)
+		}
+		var keys []string
+		for k := range matrix {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var parts []string
+		for _, k := range keys {
+			var values []string
+			for _, name := range []string{"color", "year", "x"} {
+				if v, ok := matrix[k][name]; ok {
+					values = append(values, fmt.Sprintf("%v:%v", name, v))
+				}
+			}
+			parts = append(parts, k + ":{" + strings.Join(values, ",") + "}")
+		}
+		if result := "{" + strings.Join(parts, ",") + "}"; result != tt.matrix {
+			t.Errorf("parseMatrixParams(%q).matrix = %v, want %v", tt.path, result, tt.matrix)
+		}
+	}
+}
+
+func TestDispatchMatrixParams(t *testing.T) {
+	r := NewRouter()
+	r.MatrixParams = true
+	r.Get("/cars/<part>", func(c *Context) {
+		fmt.Fprintf(c.Response, "%v:%v:%v", c.Params["part"], c.Matrix["cars"].Get("color"), c.Matrix["cars"].Get("year"))
+	})
+	r.Get("/files/<name>", func(c *Context) {
+		fmt.Fprintf(c.Response, "%v:%v", c.Params["name"], len(c.Matrix))
+	})
+
+	tests := []dispatchTest{
+		{"GET", "/cars;color=red;year=2020/doors", "doors:red:2020"},
+		{"GET", "/cars/doors;open", "doors::"},
+		{"GET", "/cars;color=dark%3Dred%3Bblue/doors%20x", "doors x:dark=red;blue:"},
+		{"GET", "/files/a%3Bb=1", "a;b=1:0"},
+		{"GET", "/files/a%3Bb%3D1;v=2", "a;b=1:1"},
+	}
+	runDispatchTests(t, tests, r)
+
+	r.MatrixParams = false
+	runDispatchTests(t, []dispatchTest{
+		{"GET", "/cars;color=red;year=2020/doors", ""},
+	}, r)
+}
+
+func TestDispatchWhen(t *testing.T) {
+	enabled := func(c *Context) bool {
+		return c.Request.Header.Get("X-Beta") == "on"
+	}
+	r := NewRouter()
+	r.Use(handleNext("use"))
+	r.Get("/users", handle("beta")).When(enabled)
+	r.Get("/users", handle("users"))
+	r.Get("/posts/<id>", handle("posts")).When(enabled).When(func(c *Context) bool {
+		return c.Params["id"] != "0"
+	})
+
+	tests := []struct {
+		path   string
+		beta   string
+		result string
+	}{
+		{"/users", "", "<use<users>use>"},
+		{"/users", "on", "<use<beta>use>"},
+		{"/posts/1", "", "<useuse>"},
+		{"/posts/1", "on", "<use<posts>{id:1,}use>"},
+		{"/posts/0", "on", "<useuse>"},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", tt.path, nil)
+		req.Header.Set("X-Beta", tt.beta)
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		if res.Body.String() != tt.result {
+			t.Errorf("Dispatch(%q, %q) = %q, want %q", tt.path, tt.beta, res.Body.String(), tt.result)
+		}
+	}
+}
+
+func TestDispatchWhenPanic(t *testing.T) {
+	r := NewRouter()
+	r.Get("/users", handle("users")).When(func(c *Context) bool {
+		panic("flags unavailable")
+	})
+	r.Error(func(c *Context) {
+		fmt.Fprintf(c.Response, "<error:%v>", c.Error)
+	})
+
+	req, _ := http.NewRequest("GET", "/users", nil)
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	if res.Body.String() != "<error:flags unavailable>" {
+		t.Errorf("Dispatch() with a panicking condition = %q, want %q", res.Body.String(), "<error:flags unavailable>")
+	}
+}
+
+func TestRouterURL(t *testing.T) {
+	r := NewRouter()
+	r.Get("/users/<id:\\d+>", handle("user")).Name = "user"
+	r.Get("/posts/<year:\\d{4}>/<slug>", handle("post")).Name = "post"
+	r.Group("/admin/<section>", func(r *Router) {
+		r.Get("/users/<id>", handle("admin/user")).Name = "admin/user"
+	})
+	r.Get("/posts(/<page:\\d+>)?", handle("posts")).Name = "posts"
+	r.Get("/files/<name>\\.txt", handle("file")).Name = "file"
+	r.Get("/assets/.*", handle("assets")).Name = "assets"
+
+	tests := []struct {
+		name   string
+		params map[string]string
+		url    string
+	}{
+		{"user", map[string]string{"id": "123"}, "/users/123"},
+		{"post", map[string]string{"year": "2015", "slug": "hello"}, "/posts/2015/hello"},
+		{"admin/user", map[string]string{"section": "main", "id": "1"}, "/admin/main/users/1"},
+		{"admin/user", nil, "/admin//users/"},
+		{"posts", map[string]string{"page": "2"}, "/posts/2"},
+		{"posts", nil, "/posts"},
+		{"file", map[string]string{"name": "a b/c"}, "/files/a%20b%2Fc.txt"},
+		{"assets", nil, ""},
+		{"unknown", nil, ""},
+	}
+
+	for _, tt := range tests {
+		if url := r.URL(tt.name, tt.params); url != tt.url {
+			t.Errorf("URL(%q, %v) = %q, want %q", tt.name, tt.params, url, tt.url)
+		}
+	}
+}
+
+func TestRouterClone(t *testing.T) {
+	r := NewRouter()
+	r.Use(handleNext("all"))
+	r.Get("/users", handle("users")).Default("page", "1")
+	r.Group("/admin", func(r *Router) {
+		r.Get("/users", handle("admin/users"))
+	}, handleNext("admin"))
+
+	c := r.Clone()
+	c.Get("/posts", handle("posts"))
+	c.Routes[1].(*Route).Defaults["page"] = "2"
+	admin := c.Routes[2].(*Router)
+	admin.Routes = nil
+	admin.Handlers[0] = handle("cloned")
+
+	if admin.Parent != c {
+		t.Errorf("Clone() did not set the parent of the child router")
+	}
+	if r.Routes[1].(*Route).Defaults["page"] != "1" {
+		t.Errorf("Clone() shares the route defaults with the original router")
+	}
+
+	runDispatchTests(t, []dispatchTest{
+		{"GET", "/posts", "<allall>"},
+		{"GET", "/admin/users", "<all<admin<admin/users>admin>all>"},
+	}, r)
+	runDispatchTests(t, []dispatchTest{
+		{"GET", "/posts", "<all<posts>all>"},
+		{"GET", "/admin/users", "<all<cloned>all>"},
+	}, c)
+}
+
 var handle = func(token string) Handler {
 	return func(c *Context) {
 		fmt.Fprint(c.Response, "<" + token + ">")
@@ -0,0 +1,185 @@
+// Copyright 2015 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// RPCBinding binds a method of an RPC-style service to a route.
+type RPCBinding struct {
+	Pattern string // the route pattern, such as "GET /users/<id>"
+	Method  string // the name of the service method, such as "GetUser"
+}
+
+var (
+	contextType = reflect.TypeOf(&Context{})
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// BindService adds routes that call the methods of an RPC-style service according to the given bindings.
+// This allows a service to be exposed as JSON-over-HTTP APIs without writing handlers for it.
+//
+// A bound method must take a pointer to a request struct, optionally preceded by a *Context parameter,
+// and return a response value and an error. For example,
+//
+//   type GetUserRequest struct {
+//       ID     int    `json:"id"`
+//       Fields string `json:"fields"`
+//   }
+//
+//   func (s *UserService) GetUser(req *GetUserRequest) (*User, error) { ... }
+//
+//   router.BindService(&UserService{}, []routing.RPCBinding{
+//       {"GET /users/<id>", "GetUser"},
+//   })
+//
+// For each request, a new request struct is populated from the JSON request body, the query parameters
+// and the URL parameters, in this order, so that URL parameters take precedence. A query or URL parameter
+// is assigned to the field whose JSON name (or field name, if there is no json tag) equals the parameter name
+// case-insensitively. If the request struct cannot be populated, an HTTPError with the status
+// http.StatusBadRequest will be triggered.
+//
+// The response value returned by the method is sent as JSON. If the method returns an error, the error
+// will be triggered as a panic so that it can be handled by the error handlers.
+//
+// BindService panics if a method cannot be found or has an unsupported signature.
+func (r *Router) BindService(service interface{}, bindings []RPCBinding) {
+	value := reflect.ValueOf(service)
+	for _, binding := range bindings {
+		method := value.MethodByName(binding.Method)
+		if !method.IsValid() {
+			panic(fmt.Sprintf("service method %q not found", binding.Method))
+		}
+		r.To(binding.Pattern, rpcHandler(binding.Method, method))
+	}
+}
+
+// rpcHandler returns a handler that calls the given service method.
+func rpcHandler(name string, method reflect.Value) Handler {
+	t := method.Type()
+	withContext := t.NumIn() == 2 && t.In(0) == contextType
+	if (t.NumIn() != 1 && !withContext) || t.NumOut() != 2 || t.Out(1) != errorType {
+		panic(fmt.Sprintf("service method %q must be in the form of func([*routing.Context, ]*Request) (Response, error)", name))
+	}
+	reqType := t.In(t.NumIn() - 1)
+	if reqType.Kind() != reflect.Ptr || reqType.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("service method %q must take a pointer to a request struct", name))
+	}
+
+	return func(c *Context) {
+		req := reflect.New(reqType.Elem())
+		if err := bindRPCRequest(c, req); err != nil {
+			c.Panic(http.StatusBadRequest, err.Error())
+		}
+
+		args := []reflect.Value{req}
+		if withContext {
+			args = []reflect.Value{reflect.ValueOf(c), req}
+		}
+		results := method.Call(args)
+		if err := results[1].Interface(); err != nil {
+			panic(err)
+		}
+
+		c.Response.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(c.Response).Encode(results[0].Interface()); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// bindRPCRequest populates the request struct pointed to by req with the request body, the query parameters and the URL parameters.
+func bindRPCRequest(c *Context, req reflect.Value) error {
+	if c.Request.Body != nil && c.Request.ContentLength != 0 {
+		if err := json.NewDecoder(c.Request.Body).Decode(req.Interface()); err != nil && err != io.EOF {
+			return err
+		}
+	}
+
+	fields := rpcFields(req.Elem())
+	for name, values := range c.Request.URL.Query() {
+		if field, ok := fields[strings.ToLower(name)]; ok && len(values) > 0 {
+			if err := setFieldValue(field, values[0]); err != nil {
+				return fmt.Errorf("invalid value for %q: %v", name, err)
+			}
+		}
+	}
+	for name, value := range c.Params {
+		if field, ok := fields[strings.ToLower(name)]; ok {
+			if err := setFieldValue(field, value); err != nil {
+				return fmt.Errorf("invalid value for %q: %v", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// rpcFields returns the settable fields of the given struct, indexed by their lower-cased JSON names.
+func rpcFields(v reflect.Value) map[string]reflect.Value {
+	fields := make(map[string]reflect.Value)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := field.Name
+		if tag := strings.Split(field.Tag.Get("json"), ",")[0]; tag == "-" {
+			continue
+		} else if tag != "" {
+			name = tag
+		}
+		fields[strings.ToLower(name)] = v.Field(i)
+	}
+	return fields
+}
+
+// setFieldValue converts the string into the type of the given field and assigns it to the field.
+func setFieldValue(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Ptr:
+		v := reflect.New(field.Type().Elem())
+		if err := setFieldValue(v.Elem(), value); err != nil {
+			return err
+		}
+		field.Set(v)
+	default:
+		return fmt.Errorf("unsupported type %v", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+// Copyright 2015 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"testing"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+type getItemRequest struct {
+	ID      int     `json:"id"`
+	Name    string  `json:"name"`
+	Limit   *uint   `json:"limit"`
+	Visible bool
+}
+
+type item struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type itemService struct{}
+
+func (s *itemService) GetItem(req *getItemRequest) (*item, error) {
+	if req.Limit != nil {
+		return &item{req.ID, fmt.Sprintf("%v:%v:%v", req.Name, *req.Limit, req.Visible)}, nil
+	}
+	return &item{req.ID, fmt.Sprintf("%v:%v", req.Name, req.Visible)}, nil
+}
+
+func (s *itemService) UpdateItem(c *Context, req *getItemRequest) (*item, error) {
+	if req.Name == "" {
+		return nil, NewHTTPError(http.StatusUnprocessableEntity)
+	}
+	return &item{req.ID, req.Name + ":" + c.Request.Method}, nil
+}
+
+func (s *itemService) Fail(req *getItemRequest) (*item, error) {
+	return nil, errors.New("failed")
+}
+
+func (s *itemService) Invalid(req getItemRequest) *item {
+	return nil
+}
+
+func TestRouterBindService(t *testing.T) {
+	r := NewRouter()
+	r.BindService(&itemService{}, []RPCBinding{
+		{"GET /items/<id>", "GetItem"},
+		{"PUT /items/<id>", "UpdateItem"},
+		{"GET /fail", "Fail"},
+	})
+	r.Error(func(c *Context) {
+		if err, ok := c.Error.(HTTPError); ok {
+			fmt.Fprintf(c.Response, "<err:%v>", err.Code())
+		} else {
+			fmt.Fprintf(c.Response, "<err:%v>", c.Error)
+		}
+	})
+
+	tests := []struct {
+		// input
+		method string
+		url    string
+		body   string
+		// output
+		result string
+	}{
+		{"GET", "/items/12?name=abc&visible=true", "", `{"id":12,"name":"abc:true"}` + "\n"},
+		{"GET", "/items/12?NAME=abc&limit=5", "", `{"id":12,"name":"abc:5:false"}` + "\n"},
+		{"GET", "/items/12?id=5", "", `{"id":12,"name":":false"}` + "\n"},
+		{"GET", "/items/abc", "", "<err:400>"},
+		{"GET", "/items/12?limit=-1", "", "<err:400>"},
+		{"PUT", "/items/12", `{"id":5,"name":"xyz"}`, `{"id":12,"name":"xyz:PUT"}` + "\n"},
+		{"PUT", "/items/12?name=q", `{"name":"xyz"}`, `{"id":12,"name":"q:PUT"}` + "\n"},
+		{"PUT", "/items/12", `{"name":`, "<err:400>"},
+		{"PUT", "/items/12", `{}`, "<err:422>"},
+		{"GET", "/fail", "", "<err:failed>"},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		if res.Body.String() != tt.result {
+			t.Errorf("Dispatch(%q, %q, %q) = %q, want %q", tt.method, tt.url, tt.body, res.Body.String(), tt.result)
+		}
+	}
+
+	for _, method := range []string{"Unknown", "Invalid"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("BindService(%q): expected panic not found", method)
+				}
+			}()
+			r.BindService(&itemService{}, []RPCBinding{{"GET /invalid", method}})
+		}()
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2015 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"net/http"
+)
+
+// Snapshot is an immutable copy of the routes of a router. It is created by Router.Compile() and can be
+// installed by Router.Swap() to replace the routes of a live router without locking.
+type Snapshot struct {
+	router *Router
+}
+
+// Compile creates a snapshot of the current routes of the router. The snapshot is not affected
+// by later changes to the router. For example, the route table can be rebuilt in the background
+// and then swapped into the router serving requests:
+//
+//   next := routing.NewRouter()
+//   // ...register routes with next
+//   router.Swap(next.Compile())
+func (r *Router) Compile() *Snapshot {
+	return &Snapshot{r.Clone()}
+}
+
+// ServeHTTP dispatches the request to the handlers of the matching route(s) in the snapshot.
+func (s *Snapshot) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	s.router.ServeHTTP(res, req)
+}
+
+// URL builds the URL path of the route with the given name in the snapshot. Please refer to Router.URL() for details.
+func (s *Snapshot) URL(name string, params map[string]string) string {
+	return s.router.URL(name, params)
+}
+
+// Swap atomically installs the given snapshot so that the router dispatches subsequent requests to the routes
+// of the snapshot instead of its own routes. It returns the previously installed snapshot. If s is nil,
+// the router goes back to dispatching requests to its own routes. Requests being served are not affected.
+//
+// Swap may be called while the router is serving requests, which allows the route table to be rebuilt
+// in the background, or two route tables to be switched for A/B testing.
+func (r *Router) Swap(s *Snapshot) *Snapshot {
+	r.swapMutex.Lock()
+	defer r.swapMutex.Unlock()
+	old, _ := r.snapshot.Load().(*Snapshot)
+	r.snapshot.Store(s)
+	return old
+}
+
+// Snapshot returns the snapshot installed by Swap(), or nil if none is installed.
+func (r *Router) Snapshot() *Snapshot {
+	s, _ := r.snapshot.Load().(*Snapshot)
+	return s
+}
@@ -0,0 +1,49 @@
+// Copyright 2015 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"testing"
+)
+
+func TestRouterSwap(t *testing.T) {
+	r := NewRouter()
+	r.Get("/users", handle("users")).Name = "users"
+
+	a := NewRouter()
+	a.Get("/users", handle("a")).Name = "users"
+	a.Get("/posts", handle("posts"))
+	snapshot := a.Compile()
+
+	// later changes to the router do not affect the snapshot
+	a.Get("/comments", handle("comments"))
+	a.Routes[0].(*Route).Pattern = "/a"
+
+	if r.Snapshot() != nil {
+		t.Errorf("Snapshot() = %v, want nil", r.Snapshot())
+	}
+	if old := r.Swap(snapshot); old != nil {
+		t.Errorf("Swap() = %v, want nil", old)
+	}
+	if r.Snapshot() != snapshot {
+		t.Errorf("Snapshot() did not return the installed snapshot")
+	}
+	runDispatchTests(t, []dispatchTest{
+		{"GET", "/users", "<a>"},
+		{"GET", "/posts", "<posts>"},
+		{"GET", "/comments", ""},
+	}, r)
+	if url := snapshot.URL("users", nil); url != "/users" {
+		t.Errorf("Snapshot.URL() = %q, want %q", url, "/users")
+	}
+
+	if old := r.Swap(nil); old != snapshot {
+		t.Errorf("Swap() did not return the previous snapshot")
+	}
+	runDispatchTests(t, []dispatchTest{
+		{"GET", "/users", "<users>"},
+		{"GET", "/posts", ""},
+	}, r)
+}
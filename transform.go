@@ -0,0 +1,399 @@
+// Copyright 2015 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// Transformer transforms response content. It reads the original content from r and writes the new content to w.
+type Transformer func(r io.Reader, w io.Writer) error
+
+// Minifiers are the built-in transformers that minify HTML, CSS, JavaScript and JSON responses.
+// They are keyed by the media types of the responses they apply to.
+var Minifiers = map[string]Transformer{
+	"text/html":              MinifyHTML,
+	"text/css":               MinifyCSS,
+	"text/javascript":        MinifyJS,
+	"application/javascript": MinifyJS,
+	"application/json":       MinifyJSON,
+}
+
+// TransformResponse returns a handler that transforms the responses of the subsequent handlers with the transformer
+// registered for the media type of the response (e.g. "text/html"). A response whose media type has a structured
+// syntax suffix, such as "application/hal+json", is also transformed by the transformer of "application/json"
+// if it has no transformer of its own. If the response does not declare its Content-Type, the media type is detected
+// from the content. For example, the following code minifies the responses of the "/pages" route group:
+//
+//   r.Group("/pages", func(gr *routing.Router) {
+//       // ...routes
+//   }, routing.TransformResponse(routing.Minifiers))
+//
+// The response is buffered by BufferResponse, unless an earlier handler already buffers it. Responses that are
+// already encoded (e.g. compressed), responses without content, and responses whose buffer is discarded are sent
+// unchanged. If a transformer fails, the original content is sent. Because the content is only transformed when
+// the subsequent handlers complete, handlers that compress responses should be placed before this handler.
+func TransformResponse(transformers map[string]Transformer) Handler {
+	handlers := []Handler{BufferResponse(0), func(c *Context) {
+		c.Next()
+		transformResponse(c, transformers)
+	}}
+	return func(c *Context) {
+		callHandlers(c, handlers)
+	}
+}
+
+// transformResponse transforms the buffered response with the transformer registered for its media type.
+func transformResponse(c *Context, transformers map[string]Transformer) {
+	rb := c.Buffer()
+	if rb == nil || rb.Len() == 0 || rb.Header().Get("Content-Encoding") != "" {
+		return
+	}
+
+	contentType := rb.Header().Get("Content-Type")
+	if contentType == "" {
+		r, err := rb.body.reader()
+		if err != nil {
+			return
+		}
+		data, _ := ioutil.ReadAll(io.LimitReader(r, 512))
+		contentType = http.DetectContentType(data)
+	}
+	mediaType := strings.ToLower(strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0]))
+
+	transformer, ok := transformers[mediaType]
+	if !ok {
+		if i := strings.LastIndex(mediaType, "+"); i >= 0 {
+			transformer, ok = transformers["application/" + mediaType[i+1:]]
+		}
+	}
+	if ok && rb.Transform(transformer) == nil {
+		rb.Header().Del("Content-Length")
+	}
+}
+
+// MinifyJSON removes insignificant whitespace from JSON content.
+func MinifyJSON(r io.Reader, w io.Writer) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return err
+	}
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+// MinifyHTML removes comments from HTML content and collapses whitespace between tags into single spaces.
+// Tags, conditional comments and the content of "pre", "textarea", "script" and "style" elements are kept unchanged.
+func MinifyHTML(r io.Reader, w io.Writer) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	for i := 0; i < len(data); {
+		switch {
+		case bytes.HasPrefix(data[i:], []byte("<!--")) && !bytes.HasPrefix(data[i:], []byte("<!--[")):
+			end := bytes.Index(data[i+4:], []byte("-->"))
+			if end < 0 {
+				i = len(data)
+			} else {
+				i += 4 + end + 3
+			}
+		case data[i] == '<':
+			n := len(data) - i
+			if tag := rawTextTag(data[i:]); tag != "" {
+				if end := bytes.Index(bytes.ToLower(data[i:]), []byte("</" + tag)); end >= 0 {
+					n = end
+				}
+			} else {
+				n = htmlTagLength(data[i:])
+			}
+			buf.Write(data[i:i+n])
+			i += n
+		case isHTMLSpace(data[i]):
+			for i < len(data) && isHTMLSpace(data[i]) {
+				i++
+			}
+			if buf.Len() == 0 || buf.Bytes()[buf.Len()-1] != ' ' {
+				buf.WriteByte(' ')
+			}
+		default:
+			buf.WriteByte(data[i])
+			i++
+		}
+	}
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+// rawTextTag returns the name of the element started by the given HTML content if the element content
+// must be kept unchanged. An empty string is returned otherwise.
+func rawTextTag(data []byte) string {
+	for _, tag := range []string{"pre", "textarea", "script", "style"} {
+		if len(data) > len(tag) + 1 && strings.EqualFold(string(data[1:len(tag)+1]), tag) {
+			if c := data[len(tag)+1]; c == '>' || c == '/' || isHTMLSpace(c) {
+				return tag
+			}
+		}
+	}
+	return ""
+}
+
+// htmlTagLength returns the length of the tag at the beginning of the given HTML content.
+// Quoted attribute values are skipped so that they may contain ">".
+func htmlTagLength(data []byte) int {
+	var quote byte
+	for i := 1; i < len(data); i++ {
+		switch c := data[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			return i + 1
+		}
+	}
+	return len(data)
+}
+
+func isHTMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// MinifyCSS removes comments from CSS content, collapses whitespace into single spaces, and removes
+// whitespace around braces, semicolons and commas. Quoted strings are kept unchanged.
+func MinifyCSS(r io.Reader, w io.Writer) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	space := false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case c == '/' && i + 1 < len(data) && data[i+1] == '*':
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end < 0 {
+				i = len(data)
+			} else {
+				i += 2 + end + 1
+			}
+			space = true
+			continue
+		case isHTMLSpace(c):
+			space = true
+			continue
+		}
+
+		if space && buf.Len() > 0 && !strings.ContainsRune("{};,", rune(c)) && !strings.ContainsRune("{};,", rune(buf.Bytes()[buf.Len()-1])) {
+			buf.WriteByte(' ')
+		}
+		space = false
+
+		if c == '"' || c == '\'' {
+			start := i
+			for i++; i < len(data) && data[i] != c; i++ {
+				if data[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(data) {
+				i = len(data) - 1
+			}
+			buf.Write(data[start:i+1])
+			continue
+		}
+		buf.WriteByte(c)
+	}
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+// MinifyJS removes comments from JavaScript content and collapses whitespace. Whitespace containing line breaks is
+// replaced with a single line break so that automatic semicolon insertion is not affected. The content is tokenized
+// so that strings, template literals and regular expression literals are kept unchanged.
+func MinifyJS(r io.Reader, w io.Writer) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	var (
+		buf            bytes.Buffer
+		braces         []bool // whether each open brace is that of a template literal substitution
+		regex          = true // whether a "/" starts a regular expression literal rather than a division
+		space, newline bool   // whether whitespace or a line break precedes the next token
+	)
+	write := func(token []byte) {
+		if buf.Len() > 0 {
+			if last := buf.Bytes()[buf.Len()-1]; newline {
+				buf.WriteByte('\n')
+			} else if space && jsSpaceNeeded(last, token[0]) {
+				buf.WriteByte(' ')
+			}
+		}
+		space, newline = false, false
+		buf.Write(token)
+	}
+
+	for i := 0; i < len(data); {
+		c, n := data[i], 1
+		switch {
+		case c == '\n' || c == '\r':
+			newline = true
+		case c == ' ' || c == '\t' || c == '\v' || c == '\f':
+			space = true
+		case c == '/' && i + 1 < len(data) && data[i+1] == '/':
+			for n = 2; i + n < len(data) && data[i+n] != '\n' && data[i+n] != '\r'; n++ {
+			}
+			space = true
+		case c == '/' && i + 1 < len(data) && data[i+1] == '*':
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end < 0 {
+				n = len(data) - i
+			} else {
+				n = end + 4
+			}
+			if bytes.ContainsAny(data[i:i+n], "\n\r") {
+				newline = true
+			} else {
+				space = true
+			}
+		case c == '"' || c == '\'':
+			n = jsStringLength(data[i:])
+			write(data[i:i+n])
+			regex = false
+		case c == '`' || c == '}' && len(braces) > 0 && braces[len(braces)-1]:
+			if c == '}' {
+				braces = braces[:len(braces)-1]
+			}
+			var substitution bool
+			n, substitution = jsTemplateLength(data[i:])
+			write(data[i:i+n])
+			if substitution {
+				braces = append(braces, true)
+			}
+			regex = substitution
+		case c == '/' && regex:
+			n = jsRegexLength(data[i:])
+			write(data[i:i+n])
+			regex = false
+		case isJSWordChar(c):
+			for i + n < len(data) && isJSWordChar(data[i+n]) {
+				n++
+			}
+			write(data[i:i+n])
+			regex = jsRegexKeywords[string(data[i:i+n])]
+		default:
+			switch c {
+			case '{':
+				braces = append(braces, false)
+			case '}':
+				if len(braces) > 0 {
+					braces = braces[:len(braces)-1]
+				}
+			}
+			if (c == '+' || c == '-') && i + 1 < len(data) && data[i+1] == c {
+				// an increment or decrement operator does not change whether a regular expression may follow
+				n = 2
+			} else {
+				regex = c != ')' && c != ']'
+			}
+			write(data[i:i+n])
+		}
+		i += n
+	}
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+// jsRegexKeywords are the JavaScript keywords after which a "/" starts a regular expression literal.
+var jsRegexKeywords = map[string]bool{
+	"return": true, "typeof": true, "instanceof": true, "in": true, "of": true, "new": true, "delete": true,
+	"void": true, "throw": true, "case": true, "do": true, "else": true, "yield": true, "await": true,
+}
+
+// isJSWordChar checks if the given byte may be part of a JavaScript identifier, keyword or number.
+// Bytes of non-ASCII characters are treated as such, so that they are kept together with adjacent words.
+func isJSWordChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '$' || c == '\\' || c >= 0x80
+}
+
+// jsSpaceNeeded checks if the whitespace between two JavaScript tokens, ending and starting with the given bytes,
+// must be kept, e.g. between two words, in "a + +b", in "a / /re/" and in "1 .toString()".
+func jsSpaceNeeded(last, next byte) bool {
+	return isJSWordChar(last) && isJSWordChar(next) || last == next && (last == '+' || last == '-') ||
+		last == '/' && (next == '/' || next == '*') || last >= '0' && last <= '9' && next == '.' ||
+		last == '<' && next == '!' || last == '-' && next == '>'
+}
+
+// jsStringLength returns the length of the quoted string at the beginning of the given JavaScript content.
+func jsStringLength(data []byte) int {
+	for i := 1; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case data[0], '\n':
+			return i + 1
+		}
+	}
+	return len(data)
+}
+
+// jsTemplateLength returns the length of the template literal part at the beginning of the given JavaScript content.
+// The part starts with "`" or with the "}" that ends a substitution, and it ends with "`" or with the "${" that starts
+// a substitution, in which case true is also returned.
+func jsTemplateLength(data []byte) (int, bool) {
+	for i := 1; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '`':
+			return i + 1, false
+		case '$':
+			if i + 1 < len(data) && data[i+1] == '{' {
+				return i + 2, true
+			}
+		}
+	}
+	return len(data), false
+}
+
+// jsRegexLength returns the length of the regular expression literal at the beginning of the given JavaScript content,
+// excluding its flags. A "/" inside a character class does not end the literal.
+func jsRegexLength(data []byte) int {
+	class := false
+	for i := 1; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '[':
+			class = true
+		case ']':
+			class = false
+		case '/':
+			if !class {
+				return i + 1
+			}
+		case '\n':
+			return i
+		}
+	}
+	return len(data)
+}
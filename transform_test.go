@@ -0,0 +1,126 @@
+// Copyright 2015 Qiang Xue. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"testing"
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+func TestMinifiers(t *testing.T) {
+	tests := []struct {
+		transformer Transformer
+		input       string
+		output      string
+	}{
+		{MinifyJSON, "{\n  \"a\": [1, 2],\n  \"b\": \"x  y\"\n}\n", `{"a":[1,2],"b":"x  y"}`},
+		{MinifyHTML, "<html>\n  <body>\n    <!-- comment -->\n    <p  class=\"a  b\">Hello   world</p>\n    <pre>  a\n  b</pre>\n  </body>\n</html>\n",
+			"<html> <body> <p  class=\"a  b\">Hello world</p> <pre>  a\n  b</pre> </body> </html> "},
+		{MinifyHTML, "<!--[if IE]><p>IE</p><![endif]-->\n<SCRIPT>\n  var a  = 1;\n</SCRIPT>", "<!--[if IE]><p>IE</p><![endif]--> <SCRIPT>\n  var a  = 1;\n</SCRIPT>"},
+		{MinifyCSS, "/* header */\nbody {\n  color: red;\n  font-family: \"Open  Sans\", serif;\n}\n\na > b , c { margin: 0 auto }\n",
+			`body{color: red;font-family: "Open  Sans",serif;}a > b,c{margin: 0 auto}`},
+		{MinifyJS, "// header\nvar  a = 1;  /* c */ var b = a + +1;\n\n\nfunction f ( x ) {\n  return x /* multi\nline */ }\n",
+			"var a=1;var b=a+ +1;\nfunction f(x){\nreturn x\n}"},
+		{MinifyJS, `var s = "a  // b\"  c", t = 'c /* d */';`, `var s="a  // b\"  c",t='c /* d */';`},
+		{MinifyJS, "var t = `a  ${ b + `c  ${d}` }  // e\n  f`;", "var t=`a  ${b+`c  ${d}`}  // e\n  f`;"},
+		{MinifyJS, `var r = /[/]\/ +/g, x = a / 2 / b; if (/'/.test(s)) return /"/;`, `var r=/[/]\/ +/g,x=a/2/b;if(/'/.test(s))return/"/;`},
+		{MinifyJS, "x = 1 .toString(); y = a++ / 2 / c; z = a / /re/.source.length;", "x=1 .toString();y=a++/2/c;z=a/ /re/.source.length;"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := tt.transformer(strings.NewReader(tt.input), &buf); err != nil {
+			t.Errorf("Minify(%q) returned error: %v", tt.input, err)
+		} else if buf.String() != tt.output {
+			t.Errorf("Minify(%q) = %q, want %q", tt.input, buf.String(), tt.output)
+		}
+	}
+
+	if err := MinifyJSON(strings.NewReader("{"), &bytes.Buffer{}); err == nil {
+		t.Errorf("MinifyJSON() with invalid JSON returned no error")
+	}
+}
+
+func TestTransformResponse(t *testing.T) {
+	upper := func(r io.Reader, w io.Writer) error {
+		var buf bytes.Buffer
+		buf.ReadFrom(r)
+		_, err := w.Write(bytes.ToUpper(buf.Bytes()))
+		return err
+	}
+	failing := func(r io.Reader, w io.Writer) error {
+		return errors.New("failed")
+	}
+
+	r := NewRouter()
+	r.Get("/raw", func(c *Context) string {
+		c.Response.Header().Set("Content-Type", "application/json")
+		return `{ "a": 1 }`
+	})
+	r.Group("/g", func(g *Router) {
+		g.Get("/json", func(c *Context) string {
+			c.Response.Header().Set("Content-Type", "application/json; charset=utf-8")
+			c.Response.Header().Set("Content-Length", "10")
+			return `{ "a": 1 }`
+		})
+		g.Get("/hal", func(c *Context) string {
+			c.Response.Header().Set("Content-Type", "application/hal+json")
+			return `{ "a": 1 }`
+		})
+		g.Get("/text", func() string {
+			return "hello"
+		})
+		g.Get("/csv", func(c *Context) string {
+			c.Response.Header().Set("Content-Type", "text/csv")
+			return "a, b"
+		})
+		g.Get("/gzip", func(c *Context) string {
+			c.Response.Header().Set("Content-Type", "text/plain")
+			c.Response.Header().Set("Content-Encoding", "gzip")
+			return "hello"
+		})
+		g.Get("/stream", func(c *Context) string {
+			c.Response.Header().Set("Content-Type", "text/plain")
+			c.DiscardBuffer()
+			return "hello"
+		})
+	}, TransformResponse(map[string]Transformer{
+		"application/json": MinifyJSON,
+		"text/plain":       upper,
+		"text/csv":         failing,
+	}))
+
+	tests := []struct {
+		// input
+		path   string
+		// output
+		body   string
+		length string
+	}{
+		{"/raw", `{ "a": 1 }`, ""},
+		{"/g/json", `{"a":1}`, "7"},
+		{"/g/hal", `{"a":1}`, "7"},
+		{"/g/text", "HELLO", "5"},
+		{"/g/csv", "a, b", "4"},
+		{"/g/gzip", "hello", "5"},
+		{"/g/stream", "hello", ""},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", tt.path, nil)
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		if res.Body.String() != tt.body {
+			t.Errorf("TransformResponse(%q) = %q, want %q", tt.path, res.Body.String(), tt.body)
+		}
+		if length := res.Header().Get("Content-Length"); length != tt.length {
+			t.Errorf("TransformResponse(%q).Content-Length = %q, want %q", tt.path, length, tt.length)
+		}
+	}
+}